@@ -0,0 +1,202 @@
+/*
+Copyright © 2022 totemcaf@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/internal/auth"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"github.com/totemcaf/test-by-example.git/internal/results"
+	"github.com/totemcaf/test-by-example.git/internal/runners"
+	"go.uber.org/zap"
+)
+
+// flowRun is a repetition of a flow to execute
+type flowRun struct {
+	flow        *model.TestFlow
+	repetition  int
+	repetitions int
+}
+
+// flowScheduler runs the flows with up to parallel flows at the same time. When more than one flow
+// runs at the same time, the output of each flow is buffered and printed when the flow ends, so the
+// output of different flows is not mixed.
+type flowScheduler struct {
+	parallel  int
+	keepGoing bool
+	debug     bool
+	logger    *zap.SugaredLogger
+	// ctx interrupts the flows, the flows not started are not executed
+	ctx context.Context
+	// values are set in the context of each flow, e.g. the values of the suite setup
+	values map[string]any
+	// timeout is the default timeout of the requests
+	timeout time.Duration
+	// tls is the global TLS configuration of the requests
+	tls *model.TLS
+	// authProviders are shared by all the flows, so they reuse the credentials obtained
+	authProviders *auth.Providers
+	// out is where the output of the flows is printed
+	out io.Writer
+
+	outputLock sync.Mutex
+	stopLock   sync.Mutex
+	stopped    bool
+}
+
+// run executes the flows, and returns their results in the same order. If a flow fails, and the
+// scheduler does not keep going, or if the flows are interrupted, the flows not started yet are not executed,
+// and their results are skipped.
+func (s *flowScheduler) run(runs []flowRun) []*results.FlowResult {
+	flowResults := make([][]*results.FlowResult, len(runs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < s.parallel; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if s.isStopped() {
+					flowResults[i] = s.skip(runs[i])
+				} else {
+					flowResults[i] = s.runFlow(runs[i])
+				}
+			}
+		}()
+	}
+
+	for i := range runs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var executed []*results.FlowResult
+	for _, runResults := range flowResults {
+		executed = append(executed, runResults...)
+	}
+	return executed
+}
+
+func (s *flowScheduler) runFlow(run flowRun) []*results.FlowResult {
+	if s.parallel == 1 {
+		return s.execute(run, s.logger, s.out)
+	}
+
+	var logs, out bytes.Buffer
+	logger := makeBufferedLogger(s.debug, &logs)
+
+	result := s.execute(run, logger.Sugar(), &out)
+	_ = logger.Sync()
+
+	s.outputLock.Lock()
+	defer s.outputLock.Unlock()
+	_, _ = io.Copy(s.out, &out)
+	_, _ = io.Copy(os.Stderr, &logs)
+
+	return result
+}
+
+// execute runs the flow once for each of its examples
+func (s *flowScheduler) execute(run flowRun, logger *zap.SugaredLogger, out io.Writer) []*results.FlowResult {
+	var flowResults []*results.FlowResult
+
+	options := []runners.Option{
+		runners.WithOutput(out),
+		runners.WithContext(s.ctx),
+		runners.WithValues(s.values),
+		runners.WithTimeout(s.timeout),
+		runners.WithTLS(s.tls),
+		runners.WithAuthProviders(s.authProviders),
+	}
+
+	for _, testRunner := range runners.NewTestRunners(run.flow, logger, options...) {
+		if s.isStopped() {
+			flowResults = append(flowResults, skipped(testRunner, run))
+			continue
+		}
+
+		logger.Infof("Start running %s (%d/%d)", run.flow.Metadata.Name, run.repetition, run.repetitions)
+		result, err := testRunner.Run()
+		result.Repetition = run.repetition
+		flowResults = append(flowResults, result)
+
+		if err != nil {
+			logger.Error(err)
+			if !s.keepGoing {
+				s.stop()
+			}
+		} else {
+			logger.Infof("Success running %s", result.Name)
+		}
+	}
+
+	return flowResults
+}
+
+// skip returns the skipped results of the flow, one for each of its examples
+func (s *flowScheduler) skip(run flowRun) []*results.FlowResult {
+	var flowResults []*results.FlowResult
+	for _, testRunner := range runners.NewTestRunners(run.flow, s.logger) {
+		flowResults = append(flowResults, skipped(testRunner, run))
+	}
+	return flowResults
+}
+
+func skipped(testRunner runners.TestRunner, run flowRun) *results.FlowResult {
+	result := testRunner.Skip()
+	result.Repetition = run.repetition
+	return result
+}
+
+func (s *flowScheduler) stop() {
+	s.stopLock.Lock()
+	defer s.stopLock.Unlock()
+	s.stopped = true
+}
+
+// isStopped returns true when a flow failed and the scheduler does not keep going, or when the flows are interrupted
+func (s *flowScheduler) isStopped() bool {
+	s.stopLock.Lock()
+	defer s.stopLock.Unlock()
+	return s.stopped || s.ctx.Err() != nil
+}
+
+// runSuiteFlow runs the suite setup or teardown flow, and returns its result and the values in its context
+func runSuiteFlow(ctx context.Context, flow *model.TestFlow, logger *zap.SugaredLogger, values map[string]any, options ...runners.Option) (*results.FlowResult, map[string]any) {
+	options = append([]runners.Option{runners.WithContext(ctx), runners.WithValues(values)}, options...)
+	testRunner := runners.NewTestRunner(flow, logger, options...)
+
+	logger.Infof("Start running suite flow %s", flow.Metadata.Name)
+	result, err := testRunner.Run()
+
+	if err != nil {
+		logger.Error(err)
+	} else {
+		logger.Infof("Success running %s", flow.Metadata.Name)
+	}
+
+	return result, testRunner.Values()
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"github.com/totemcaf/test-by-example.git/internal/results"
+	"go.uber.org/zap"
+)
+
+// concurrencyServer responds after a delay, and records the maximum number of requests at the same time.
+// Requests to /fail respond 500
+type concurrencyServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	inFlight int
+	max      int
+}
+
+func newConcurrencyServer(delay time.Duration) *concurrencyServer {
+	server := &concurrencyServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.lock.Lock()
+		server.inFlight++
+		if server.inFlight > server.max {
+			server.max = server.inFlight
+		}
+		server.lock.Unlock()
+
+		time.Sleep(delay)
+
+		server.lock.Lock()
+		server.inFlight--
+		server.lock.Unlock()
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	return server
+}
+
+func (s *concurrencyServer) maxInFlight() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.max
+}
+
+// newFlowRuns returns a run of a flow for each name. Each flow sends steps requests to the path with its name,
+// with its name in a header
+func newFlowRuns(url string, steps int, names ...string) []flowRun {
+	var runs []flowRun
+	for _, name := range names {
+		var specs []model.StepSpec
+		for i := 0; i < steps; i++ {
+			path := url + "/" + name
+			specs = append(specs, model.StepSpec{Get: &path, Headers: model.Headers{"X-Flow": name}, Response: &model.Response{StatusCode: 200}})
+		}
+		flow := &model.TestFlow{
+			ApiVersion: model.ApiVersion,
+			Kind:       model.TestFlowKind,
+			Metadata:   model.Metadata{Name: name},
+			Spec:       model.TestFlowSpec{Steps: specs},
+		}
+		runs = append(runs, flowRun{flow, 1, 1})
+	}
+	return runs
+}
+
+func newScheduler(parallel int, keepGoing bool, out *bytes.Buffer) *flowScheduler {
+	return &flowScheduler{
+		parallel:  parallel,
+		keepGoing: keepGoing,
+		logger:    zap.NewNop().Sugar(),
+		ctx:       context.Background(),
+		out:       out,
+	}
+}
+
+func resultNames(flowResults []*results.FlowResult) []string {
+	var names []string
+	for _, result := range flowResults {
+		names = append(names, result.Name)
+	}
+	return names
+}
+
+func resultStatuses(flowResults []*results.FlowResult) []results.Status {
+	var statuses []results.Status
+	for _, result := range flowResults {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func Test_scheduler_runs_up_to_parallel_flows_at_the_same_time(t *testing.T) {
+	// GIVEN a server that records the requests at the same time
+	server := newConcurrencyServer(30 * time.Millisecond)
+	defer server.Close()
+
+	// WHEN 4 flows run with 2 at the same time
+	var out bytes.Buffer
+	flowResults := newScheduler(2, false, &out).run(newFlowRuns(server.URL, 1, "a", "b", "c", "d"))
+
+	// THEN all the flows run, 2 at a time, and the results are in the order of the flows
+	assert.Equal(t, []string{"a", "b", "c", "d"}, resultNames(flowResults))
+	assert.Equal(t, 2, server.maxInFlight())
+}
+
+func Test_scheduler_stops_after_a_failed_flow_unless_it_keeps_going(t *testing.T) {
+	tests := []struct {
+		name       string
+		keepGoing  bool
+		wantStatus []results.Status
+	}{
+		{name: "stop", keepGoing: false, wantStatus: []results.Status{results.Failed, results.Skipped, results.Skipped}},
+		{name: "keep going", keepGoing: true, wantStatus: []results.Status{results.Failed, results.Passed, results.Passed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN a server where the first flow fails
+			server := newConcurrencyServer(0)
+			defer server.Close()
+
+			// WHEN the flows run one at a time
+			var out bytes.Buffer
+			flowResults := newScheduler(1, tt.keepGoing, &out).run(newFlowRuns(server.URL, 1, "fail", "b", "c"))
+
+			// THEN the flows after the failure only run when keeping going, otherwise they are skipped
+			assert.Equal(t, []string{"fail", "b", "c"}, resultNames(flowResults))
+			assert.Equal(t, tt.wantStatus, resultStatuses(flowResults))
+		})
+	}
+}
+
+func Test_scheduler_does_not_run_flows_when_interrupted(t *testing.T) {
+	// GIVEN an interrupted run
+	server := newConcurrencyServer(0)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	scheduler := newScheduler(2, true, &out)
+	scheduler.ctx = ctx
+
+	// WHEN the flows run
+	flowResults := scheduler.run(newFlowRuns(server.URL, 2, "a", "b"))
+
+	// THEN no flow is started, and the flows and their steps are skipped
+	assert.Equal(t, []string{"a", "b"}, resultNames(flowResults))
+	assert.Equal(t, []results.Status{results.Skipped, results.Skipped}, resultStatuses(flowResults))
+	for _, result := range flowResults {
+		assert.Equal(t, 2, result.Count(results.Skipped))
+	}
+	assert.Empty(t, out.String())
+}
+
+func Test_scheduler_does_not_mix_the_output_of_parallel_flows(t *testing.T) {
+	// GIVEN a server that responds slowly, so the flows run at the same time
+	server := newConcurrencyServer(10 * time.Millisecond)
+	defer server.Close()
+
+	// WHEN 3 flows with 3 steps each run at the same time
+	var out bytes.Buffer
+	newScheduler(3, false, &out).run(newFlowRuns(server.URL, 3, "a", "b", "c"))
+
+	// THEN the output of each flow is printed together
+	var flows []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if name := strings.TrimPrefix(line, "Using Header: X-Flow: "); name != line {
+			flows = append(flows, name)
+		}
+	}
+	assert.Len(t, flows, 9)
+	for i := 0; i < len(flows); i += 3 {
+		assert.Equal(t, []string{flows[i], flows[i], flows[i]}, flows[i:i+3])
+	}
+	assert.Equal(t, 3, server.maxInFlight())
+}
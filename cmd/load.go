@@ -0,0 +1,124 @@
+/*
+Copyright © 2022 totemcaf@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/totemcaf/test-by-example.git/internal/load"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const loadLongDescription = `
+"load" reads the test flows like "run" does, and runs them with several virtual users at the same time.
+
+Each virtual user runs the flows in order, again and again, each time with its own context and new generated
+values. The virtual users are started during the ramp-up, and they stop when the duration ends or when they
+repeat the flows the given number of times.
+
+At the end, the latency percentiles, the requests and the error rate of each step are printed, with the
+throughput of the test. The exit code is 1 if any flow failed, and the same as "run" for other errors.
+`
+
+// loadCmd represents the load command
+var loadCmd = &cobra.Command{
+	Use:   "load pathToFiles [pathToFiles ...]",
+	Short: "Executes a test suite as a load test",
+	Long:  loadLongDescription,
+	Args:  cobra.MatchAll(cobra.MinimumNArgs(1), validateFilesOrFolders),
+	RunE:  executeLoad,
+}
+
+func init() {
+	rootCmd.AddCommand(loadCmd)
+
+	_ = loadCmd.Flags().IntP("users", "u", 1, "number of virtual users running the flows at the same time")
+	_ = loadCmd.Flags().Duration("ramp-up", 0, "time to start all the virtual users")
+	_ = loadCmd.Flags().Duration("duration", 0, "total time of the test, including the ramp-up")
+	_ = loadCmd.Flags().IntP("repetitions", "r", 0, "times each virtual user runs the flows, 0 is no limit")
+	_ = loadCmd.Flags().Float64("rate", 0, "target requests per second of all the virtual users, 0 is no limit")
+	_ = loadCmd.Flags().StringP("suite", "s", "", "only run the suites with the given names, separated by comma")
+	_ = loadCmd.Flags().BoolP("debug", "d", false, "enable debug logging")
+
+	// Keys are prefixed to not collide with the flags of the run command
+	for _, name := range []string{"users", "ramp-up", "duration", "repetitions", "rate", "suite", "debug"} {
+		if err := viper.BindPFlag("load."+name, loadCmd.Flags().Lookup(name)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func executeLoad(cmd *cobra.Command, paths []string) error {
+	config := load.Config{
+		Users:       viper.GetInt("load.users"),
+		RampUp:      viper.GetDuration("load.ramp-up"),
+		Duration:    viper.GetDuration("load.duration"),
+		Repetitions: viper.GetInt("load.repetitions"),
+		Rate:        viper.GetFloat64("load.rate"),
+		Timeout:     viper.GetDuration("timeout"),
+	}
+
+	if err := config.Validate(); err != nil {
+		return newExitError(exitUsageError, "%w", err)
+	}
+
+	tls, err := readTLS()
+	if err != nil {
+		return newExitError(exitUsageError, "%w", err)
+	}
+	config.TLS = tls
+
+	// Arguments were validated, errors from now on are not usage errors
+	cmd.SilenceUsage = true
+
+	debug := viper.GetBool("load.debug")
+	l := makeLogger(debug)
+
+	defer func() {
+		_ = l.Sync()
+	}()
+
+	logger := l.Sugar()
+	testFlows, err := readTestFlows(logger, expandPaths(paths), viper.GetString("load.suite"))
+
+	if err != nil {
+		return err
+	}
+
+	// The progress of each virtual user is only logged when debugging
+	usersLogger := logger
+	if !debug {
+		usersLogger = l.WithOptions(zap.IncreaseLevel(zapcore.WarnLevel)).Sugar()
+	}
+
+	logger.Infof("Starting %d virtual users", config.Users)
+	stats := load.Run(config, testFlows, usersLogger)
+
+	if err := stats.Write(os.Stdout); err != nil {
+		logger.Errorf("Failed to write statistics: %s", err.Error())
+	}
+
+	if stats.FailedFlows > 0 {
+		return newExitError(exitTestFailure, "some flows failed")
+	}
+
+	return nil
+}
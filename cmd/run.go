@@ -18,16 +18,22 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/totemcaf/test-by-example.git/internal/auth"
 	"github.com/totemcaf/test-by-example.git/internal/model"
 	"github.com/totemcaf/test-by-example.git/internal/parsers"
+	"github.com/totemcaf/test-by-example.git/internal/reporters"
+	"github.com/totemcaf/test-by-example.git/internal/results"
 	"github.com/totemcaf/test-by-example.git/internal/runners"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -41,6 +47,13 @@ Test suite description files are YAML files that contain a list of tests to exec
 You can list the files to process, use globs, or point to folders with test suite description files.
 
 All files will be read and combined.
+
+The run stops at the first failed flow. Use --keep-going to run all the flows.
+
+Use --parallel to run several flows at the same time. The output of each flow is printed when it ends.
+
+At the end, a summary of the flows is printed. The exit code is 0 if all flows passed, 1 if any flow
+failed, 2 if the files cannot be read or are not valid, and 3 if the command is not used correctly.
 `
 
 // runCmd represents the run command
@@ -50,7 +63,7 @@ var runCmd = &cobra.Command{
 	Short:   "Executes a test suite",
 	Long:    longDescription,
 	Args:    cobra.MatchAll(cobra.MinimumNArgs(1), validateFilesOrFolders),
-	Run:     executeRun,
+	RunE:    executeRun,
 }
 
 func validateFilesOrFolders(_ *cobra.Command, args []string) error {
@@ -68,20 +81,80 @@ func init() {
 	_ = runCmd.Flags().IntP("repetitions", "r", 1, "times to execute the test suite")
 	_ = runCmd.Flags().StringP("suite", "s", "", "if multiple suites are found, only run the suite with the given name")
 	_ = runCmd.Flags().BoolP("debug", "d", false, "enable debug logging")
+	_ = runCmd.Flags().BoolP("keep-going", "k", false, "run all the flows, even when some of them fail")
+	_ = runCmd.Flags().IntP("parallel", "p", 1, "number of flows to run at the same time")
+	_ = runCmd.Flags().String("setup", "", "name of a flow to run before all the flows. Its values are visible in all the flows")
+	_ = runCmd.Flags().String("teardown", "", "name of a flow to run after all the flows, even if they fail or are interrupted")
+	_ = runCmd.Flags().StringSlice("report", nil, "write a report of the run as format=path (formats: json, junit). Can be repeated")
+	_ = runCmd.Flags().Duration("timeout", 0, "default timeout of the requests, e.g. 30s. Steps can set their own timeout")
 
 	err := viper.BindPFlag("repetitions", runCmd.Flags().Lookup("repetitions"))
 	if err != nil {
 		panic(err)
 	}
+
+	err = viper.BindPFlag("suite", runCmd.Flags().Lookup("suite"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = viper.BindPFlag("debug", runCmd.Flags().Lookup("debug"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = viper.BindPFlag("keep-going", runCmd.Flags().Lookup("keep-going"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = viper.BindPFlag("parallel", runCmd.Flags().Lookup("parallel"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = viper.BindPFlag("setup", runCmd.Flags().Lookup("setup"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = viper.BindPFlag("teardown", runCmd.Flags().Lookup("teardown"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = viper.BindPFlag("report", runCmd.Flags().Lookup("report"))
+	if err != nil {
+		panic(err)
+	}
+
+	err = viper.BindPFlag("timeout", runCmd.Flags().Lookup("timeout"))
+	if err != nil {
+		panic(err)
+	}
 }
 
-func executeRun(_ *cobra.Command, paths []string) {
+func executeRun(cmd *cobra.Command, paths []string) error {
+	if viper.GetInt("parallel") < 1 {
+		return newExitError(exitUsageError, "parallel must be at least 1")
+	}
+
+	// Arguments were validated, errors from now on are not usage errors
+	cmd.SilenceUsage = true
 
 	files := expandPaths(paths)
 
 	repetitions := viper.GetInt("repetitions")
 	suiteToExecute := viper.GetString("suite")
 	debug := viper.GetBool("debug")
+	keepGoing := viper.GetBool("keep-going")
+	parallel := viper.GetInt("parallel")
+	timeout := viper.GetDuration("timeout")
+
+	tls, err := readTLS()
+	if err != nil {
+		return newExitError(exitUsageError, "%w", err)
+	}
 
 	fmt.Println("Echo: " + strings.Join(files, " "))
 
@@ -92,39 +165,204 @@ func executeRun(_ *cobra.Command, paths []string) {
 	}()
 
 	logger := l.Sugar()
+	testFlowCollection, err := readTestFlowCollection(logger, files)
+
+	if err != nil {
+		return err
+	}
+
+	setupFlow, err := getSuiteFlow(testFlowCollection, viper.GetString("setup"))
+
+	if err != nil {
+		return err
+	}
+
+	teardownFlow, err := getSuiteFlow(testFlowCollection, viper.GetString("teardown"))
+
+	if err != nil {
+		return err
+	}
+
+	testFlows, err := selectTestFlows(testFlowCollection, suiteToExecute, setupFlow, teardownFlow)
+
+	if err != nil {
+		return err
+	}
+
+	reportWriters, err := makeReporters(viper.GetStringSlice("report"))
+
+	if err != nil {
+		return newExitError(exitUsageError, "%w", err)
+	}
+
+	var runs []flowRun
+	for repetition := 1; repetition <= repetitions; repetition++ {
+		for _, testFlow := range testFlows {
+			runs = append(runs, flowRun{testFlow, repetition, repetitions})
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		// After the first interrupt, the teardown steps are run. A second interrupt ends the process
+		<-ctx.Done()
+		stop()
+	}()
+
+	report := results.NewReport()
+	var suiteValues map[string]any
+
+	// The flows share the auth providers, so they reuse the credentials obtained, e.g. OAuth2 tokens
+	authProviders := auth.NewProviders()
+	suiteOptions := []runners.Option{runners.WithTimeout(timeout), runners.WithTLS(tls), runners.WithAuthProviders(authProviders)}
+
+	if setupFlow != nil {
+		var result *results.FlowResult
+		result, suiteValues = runSuiteFlow(ctx, setupFlow, logger, nil, suiteOptions...)
+		report.Add(result)
+	}
+
+	if report.Passed() {
+		scheduler := &flowScheduler{
+			parallel:      parallel,
+			keepGoing:     keepGoing,
+			debug:         debug,
+			logger:        logger,
+			ctx:           ctx,
+			values:        suiteValues,
+			timeout:       timeout,
+			tls:           tls,
+			out:           os.Stdout,
+			authProviders: authProviders,
+		}
+
+		for _, result := range scheduler.run(runs) {
+			report.Add(result)
+		}
+	} else {
+		logger.Errorf("Suite setup failed, the flows are not executed")
+	}
+
+	if teardownFlow != nil {
+		// The suite teardown runs even if the run is interrupted
+		result, _ := runSuiteFlow(context.Background(), teardownFlow, logger, suiteValues, suiteOptions...)
+		report.Add(result)
+	}
+
+	report.Finish()
+	writeReports(logger, append(reportWriters, reporters.NewSummaryReporter(os.Stdout)), report)
+
+	if !report.Passed() {
+		return newExitError(exitTestFailure, "some flows failed")
+	}
+
+	if ctx.Err() != nil {
+		return newExitError(exitTestFailure, "interrupted")
+	}
+
+	return nil
+}
+
+// readTestFlows reads the flows in the files, and returns the ones to execute
+func readTestFlows(logger *zap.SugaredLogger, files []string, suiteToExecute string) ([]*model.TestFlow, error) {
+	testFlowCollection, err := readTestFlowCollection(logger, files)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return selectTestFlows(testFlowCollection, suiteToExecute)
+}
+
+func readTestFlowCollection(logger *zap.SugaredLogger, files []string) (model.TestFlowCollection, error) {
 	testFlowCollection, err := parsers.ReadTestFlowCollectionFrom(logger, files)
 
 	if err != nil {
-		logger.Error(err.Error())
-		return
+		return testFlowCollection, newExitError(exitParseError, "%w", err)
 	}
 
+	return testFlowCollection, nil
+}
+
+// selectTestFlows returns the flows to execute, without the excluded flows, e.g. the suite setup and teardown
+func selectTestFlows(testFlowCollection model.TestFlowCollection, suiteToExecute string, excluded ...*model.TestFlow) ([]*model.TestFlow, error) {
 	suiteNames, err := verifySuitesToExecute(suiteToExecute, testFlowCollection)
 
 	if err != nil {
-		logger.Error(err.Error())
-		return
+		return nil, newExitError(exitUsageError, "%w", err)
 	}
 
-	for repetition := 1; repetition <= repetitions; repetition++ {
-		for _, suiteName := range suiteNames {
-			testFlow, _ := testFlowCollection.GetTestFlow(suiteName)
-			testRunner := runners.NewTestRunner(testFlow, logger)
+	var testFlows []*model.TestFlow
+	for _, suiteName := range suiteNames {
+		testFlow, _ := testFlowCollection.GetTestFlow(suiteName)
+		if !isExcluded(testFlow, excluded) {
+			testFlows = append(testFlows, testFlow)
+		}
+	}
+
+	return testFlows, nil
+}
+
+func isExcluded(testFlow *model.TestFlow, excluded []*model.TestFlow) bool {
+	for _, flow := range excluded {
+		if flow == testFlow {
+			return true
+		}
+	}
+	return false
+}
+
+// getSuiteFlow returns the flow with the given name, or nil if no name is given
+func getSuiteFlow(testFlowCollection model.TestFlowCollection, name string) (*model.TestFlow, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	testFlow, found := testFlowCollection.GetTestFlow(name)
+	if !found {
+		return nil, newExitError(exitUsageError, "flow '%s' not found", name)
+	}
 
-			logger.Infof("Start running %s (%d/%d)", testFlow.Metadata.Name, repetition, repetitions)
-			err = testRunner.Run()
+	return testFlow, nil
+}
 
-			if err != nil {
-				logger.Error(err)
-				break
-			}
+func makeReporters(definitions []string) ([]reporters.Reporter, error) {
+	var result []reporters.Reporter
 
-			logger.Infof("Success running %s", testFlow.Metadata.Name)
+	for _, definition := range definitions {
+		reporter, err := reporters.New(definition)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, reporter)
+	}
+
+	return result, nil
+}
+
+func writeReports(logger *zap.SugaredLogger, reportWriters []reporters.Reporter, report *results.Report) {
+	for _, reporter := range reportWriters {
+		if err := reporter.Write(report); err != nil {
+			logger.Errorf("Failed to write report: %s", err.Error())
 		}
 	}
 }
 
 func makeLogger(debug bool) *zap.Logger {
+	return zap.Must(loggerConfig(debug).Build())
+}
+
+// makeBufferedLogger returns a logger like the one from makeLogger, but it writes to the given writer
+func makeBufferedLogger(debug bool, writer io.Writer) *zap.Logger {
+	config := loggerConfig(debug)
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(config.EncoderConfig), zapcore.AddSync(writer), config.Level)
+
+	return zap.New(core)
+}
+
+func loggerConfig(debug bool) zap.Config {
 
 	var config zap.Config
 
@@ -137,7 +375,7 @@ func makeLogger(debug bool) *zap.Logger {
 		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	}
 
-	return zap.Must(config.Build())
+	return config
 }
 
 func verifySuitesToExecute(suiteToExecute string, testFlowCollection model.TestFlowCollection) ([]string, error) {
@@ -172,17 +410,22 @@ func checkSuiteNames(flow model.TestFlowCollection, names []string) error {
 	return nil
 }
 
+// expandPaths returns the files of all the paths
 func expandPaths(paths []string) []string {
 	var files []string
 	for _, path := range paths {
-		files = getFiles(path)
+		files = append(files, getFiles(path)...)
 	}
 
 	return files
 }
 
+// getFiles returns the path if it is a file, or the YAML files in the folder and its sub folders
 func getFiles(path string) []string {
 	var files []string
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return append(files, path)
+	}
 	fileInfos, err := ioutil.ReadDir(path)
 	if err != nil {
 		return files
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFlow writes a flow with the given steps in the folder
+func writeFlow(t *testing.T, dir string, name string, steps string) {
+	spec := fmt.Sprintf("apiVersion: test/v1-alpha\nkind: TestFlow\nmetadata:\n  name: %s\nspec:\n  steps:\n%s", name, steps)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(spec), 0600))
+}
+
+// getStep returns a step that gets the path and expects a 200 status
+func getStep(url string, path string) string {
+	return fmt.Sprintf("    - get: %s%s\n      response:\n        statusCode: 200\n", url, path)
+}
+
+func Test_run_suite_setup_and_teardown(t *testing.T) {
+	// GIVEN a server that returns a token, interrupts the run on /interrupt, and records the paths requested
+	var lock sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		paths = append(paths, r.URL.Path)
+		lock.Unlock()
+
+		if r.URL.Path == "/interrupt" {
+			assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+			// Waits for the signal, so the run is interrupted during the request
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token": "abc"}`))
+	}))
+	defer server.Close()
+
+	// AND a setup flow that extracts the token, and a teardown flow that uses it
+	dir := t.TempDir()
+	writeFlow(t, dir, "setup", fmt.Sprintf("    - post: %s/setup\n      response:\n        statusCode: 200\n        body:\n          token: $(token)\n", server.URL))
+	writeFlow(t, dir, "teardown", getStep(server.URL, "/teardown/${token}"))
+
+	viper.Set("setup", "setup")
+	viper.Set("teardown", "teardown")
+	defer func() {
+		viper.Set("setup", "")
+		viper.Set("teardown", "")
+	}()
+
+	tests := []struct {
+		name      string
+		steps     string
+		wantErr   string
+		wantPaths []string
+	}{
+		{
+			name:      "setup values reach the flows",
+			steps:     getStep(server.URL, "/flow/${token}"),
+			wantPaths: []string{"/setup", "/flow/abc", "/teardown/abc"},
+		},
+		{
+			name:      "teardown after an interruption",
+			steps:     getStep(server.URL, "/interrupt") + getStep(server.URL, "/never"),
+			wantErr:   "some flows failed",
+			wantPaths: []string{"/setup", "/interrupt", "/teardown/abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths = nil
+			writeFlow(t, dir, "flow", tt.steps)
+
+			// WHEN the suite runs
+			err := executeRun(runCmd, []string{dir})
+
+			// THEN the setup runs first, its values are visible in the flows, and the teardown runs last
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantPaths, paths)
+		})
+	}
+}
+
+func Test_run_paths_and_suite(t *testing.T) {
+	// GIVEN a server that records the paths requested
+	var lock sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		paths = append(paths, r.URL.Path)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	// AND flows in two folders, and in a file of a third folder
+	dirs := []string{t.TempDir(), t.TempDir(), t.TempDir()}
+	writeFlow(t, dirs[0], "first", getStep(server.URL, "/first"))
+	writeFlow(t, dirs[1], "second", getStep(server.URL, "/second"))
+	writeFlow(t, dirs[2], "third", getStep(server.URL, "/third"))
+	writeFlow(t, dirs[2], "ignored", getStep(server.URL, "/ignored"))
+	args := []string{dirs[0], dirs[1], filepath.Join(dirs[2], "third.yaml")}
+
+	tests := []struct {
+		name         string
+		suite        string
+		wantExitCode int
+		wantPaths    []string
+	}{
+		{
+			name:      "all the paths",
+			wantPaths: []string{"/first", "/second", "/third"},
+		},
+		{
+			name:      "selected suite",
+			suite:     "second",
+			wantPaths: []string{"/second"},
+		},
+		{
+			name:         "unknown suite",
+			suite:        "unknown",
+			wantExitCode: exitUsageError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths = nil
+			assert.NoError(t, runCmd.Flags().Set("suite", tt.suite))
+			defer func() {
+				_ = runCmd.Flags().Set("suite", "")
+			}()
+
+			// WHEN the flows of the paths are run
+			err := executeRun(runCmd, args)
+
+			// THEN the flows of every path are run, only the selected suite when given, and an unknown suite is
+			// a usage error
+			if tt.wantExitCode != 0 {
+				var exitErr *exitError
+				if assert.True(t, errors.As(err, &exitErr)) {
+					assert.Equal(t, tt.wantExitCode, exitErr.code)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.ElementsMatch(t, tt.wantPaths, paths)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2022 totemcaf@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/spf13/viper"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	_ = flags.StringSlice("ca-cert", nil, "PEM file with the certificate of a CA to trust, besides the CAs of the system. Can be repeated")
+	_ = flags.String("client-cert", "", "PEM file with the client certificate sent when the server requests it")
+	_ = flags.String("client-key", "", "PEM file with the private key of the client certificate")
+	_ = flags.String("server-name", "", "name used to verify the certificate of the servers, by default the host of the request")
+	_ = flags.Bool("insecure", false, "accept any certificate of the servers. Use it only in tests")
+
+	// The flags are also set with the tls keys of the configuration file
+	for key, name := range map[string]string{
+		"tls.caCerts":            "ca-cert",
+		"tls.clientCert":         "client-cert",
+		"tls.clientKey":          "client-key",
+		"tls.serverName":         "server-name",
+		"tls.insecureSkipVerify": "insecure",
+	} {
+		if err := viper.BindPFlag(key, flags.Lookup(name)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// readTLS returns the global TLS configuration, or nil if it is not set. Relative paths are resolved from the
+// working folder
+func readTLS() (*model.TLS, error) {
+	tls := &model.TLS{
+		CACerts:            viper.GetStringSlice("tls.caCerts"),
+		ServerName:         viper.GetString("tls.serverName"),
+		InsecureSkipVerify: viper.GetBool("tls.insecureSkipVerify"),
+	}
+
+	if cert, key := viper.GetString("tls.clientCert"), viper.GetString("tls.clientKey"); cert != "" || key != "" {
+		tls.ClientCerts = []model.ClientCert{{Cert: cert, Key: key}}
+	}
+
+	if len(tls.CACerts) == 0 && tls.ClientCerts == nil && tls.ServerName == "" && !tls.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	return tls, tls.Validate()
+}
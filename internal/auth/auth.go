@@ -0,0 +1,114 @@
+// Package auth adds the credentials of the flow auth to the requests of its steps
+package auth
+
+import (
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/totemcaf/test-by-example.git/internal/contexts"
+	"github.com/totemcaf/test-by-example.git/internal/evaluators"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+// Provider adds credentials to the requests
+type Provider interface {
+	// Authenticate adds the credentials to the request. Their values are evaluated in the given context
+	Authenticate(request *resty.Request, context contexts.RunningContext) error
+	// Renew discards the cached credentials of the context, e.g. after a 401 Unauthorized response. It returns
+	// true if new credentials are obtained in the next request, so it can be sent again
+	Renew(context contexts.RunningContext) bool
+}
+
+// New returns the provider of the given auth, or nil if there is no auth. The client sends the requests
+// to obtain the credentials, e.g. the OAuth2 tokens
+func New(spec *model.Auth, client *resty.Client) Provider {
+	switch {
+	case spec == nil:
+		return nil
+	case spec.Basic != nil:
+		return &basicProvider{*spec.Basic}
+	case spec.Bearer != nil:
+		return &bearerProvider{*spec.Bearer}
+	case spec.ApiKey != nil:
+		return &apiKeyProvider{*spec.ApiKey}
+	case spec.OAuth2 != nil:
+		return newOAuth2Provider(*spec.OAuth2, client)
+	}
+	return nil
+}
+
+// Providers keeps the provider of each auth, so the runners of a flow share the credentials obtained, e.g. the
+// OAuth2 tokens. It is safe to use from several runners at the same time
+type Providers struct {
+	lock      sync.Mutex
+	providers map[*model.Auth]Provider
+}
+
+func NewProviders() *Providers {
+	return &Providers{providers: make(map[*model.Auth]Provider)}
+}
+
+// Get returns the provider of the auth, or nil if there is no auth. The provider is created with the client the
+// first time, see New
+func (p *Providers) Get(spec *model.Auth, client *resty.Client) Provider {
+	if spec == nil {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	provider, found := p.providers[spec]
+	if !found {
+		provider = New(spec, client)
+		p.providers[spec] = provider
+	}
+	return provider
+}
+
+type basicProvider struct {
+	spec model.BasicAuth
+}
+
+func (p *basicProvider) Authenticate(request *resty.Request, context contexts.RunningContext) error {
+	eval := evaluators.NewJsonXEvaluator(context)
+	request.SetBasicAuth(eval.EvaluateStr(p.spec.Username), eval.EvaluateStr(p.spec.Password))
+	return nil
+}
+
+func (p *basicProvider) Renew(contexts.RunningContext) bool {
+	return false
+}
+
+type bearerProvider struct {
+	spec model.BearerAuth
+}
+
+func (p *bearerProvider) Authenticate(request *resty.Request, context contexts.RunningContext) error {
+	request.SetAuthToken(evaluators.NewJsonXEvaluator(context).EvaluateStr(p.spec.Token))
+	return nil
+}
+
+func (p *bearerProvider) Renew(contexts.RunningContext) bool {
+	return false
+}
+
+type apiKeyProvider struct {
+	spec model.ApiKeyAuth
+}
+
+func (p *apiKeyProvider) Authenticate(request *resty.Request, context contexts.RunningContext) error {
+	eval := evaluators.NewJsonXEvaluator(context)
+	name, value := eval.EvaluateStr(p.spec.Name), eval.EvaluateStr(p.spec.Value)
+
+	if p.spec.In == model.ApiKeyInQuery {
+		request.SetQueryParam(name, value)
+	} else {
+		request.SetHeader(name, value)
+	}
+	return nil
+}
+
+func (p *apiKeyProvider) Renew(contexts.RunningContext) bool {
+	return false
+}
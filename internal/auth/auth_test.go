@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/contexts"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"go.uber.org/zap"
+)
+
+// received are the credentials received by the server of the tests
+type received struct {
+	authorization string
+	apiKeyHeader  string
+	apiKeyQuery   string
+}
+
+func newApiServer(got *received) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.authorization = r.Header.Get("Authorization")
+		got.apiKeyHeader = r.Header.Get("Api-Key")
+		got.apiKeyQuery = r.URL.Query().Get("api_key")
+	}))
+}
+
+func newContext(values map[string]any) contexts.RunningContext {
+	context := contexts.NewRunningContext(zap.NewNop().Sugar())
+	for name, value := range values {
+		context.Set(name, value)
+	}
+	return context
+}
+
+func Test_static_providers(t *testing.T) {
+	var got received
+	server := newApiServer(&got)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		spec *model.Auth
+		want received
+	}{
+		{
+			name: "basic",
+			spec: &model.Auth{Basic: &model.BasicAuth{Username: "${user}", Password: "secret"}},
+			want: received{authorization: "Basic YW5hOnNlY3JldA=="},
+		},
+		{
+			name: "bearer",
+			spec: &model.Auth{Bearer: &model.BearerAuth{Token: "${token}"}},
+			want: received{authorization: "Bearer t0k3n"},
+		},
+		{
+			name: "api key in header",
+			spec: &model.Auth{ApiKey: &model.ApiKeyAuth{Name: "Api-Key", Value: "${key}"}},
+			want: received{apiKeyHeader: "k3y"},
+		},
+		{
+			name: "api key in query",
+			spec: &model.Auth{ApiKey: &model.ApiKeyAuth{Name: "api_key", Value: "${key}", In: model.ApiKeyInQuery}},
+			want: received{apiKeyQuery: "k3y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN a provider, and the values of its credentials
+			provider := New(tt.spec, resty.New())
+			context := newContext(map[string]any{"user": "ana", "token": "t0k3n", "key": "k3y"})
+			request := resty.New().R()
+
+			// WHEN a request is authenticated and sent
+			assert.NoError(t, provider.Authenticate(request, context))
+			_, err := request.Get(server.URL)
+
+			// THEN the server receives the credentials, and they cannot be renewed
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.False(t, provider.Renew(context))
+		})
+	}
+}
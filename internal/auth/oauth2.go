@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/totemcaf/test-by-example.git/internal/contexts"
+	"github.com/totemcaf/test-by-example.git/internal/evaluators"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+// oauth2Provider requests an access token to the token endpoint, and keeps it until it expires or it is renewed.
+// A token is kept for each token request, e.g. for each client, and it is safe to use from several runners at the
+// same time
+type oauth2Provider struct {
+	spec   model.OAuth2Auth
+	client *resty.Client
+	now    func() time.Time
+
+	lock sync.Mutex
+	// tokens are the cached tokens by their token request
+	tokens map[string]oauth2Token
+}
+
+type oauth2Token struct {
+	value string
+	// expiry is when the token is requested again, zero if the token does not expire
+	expiry time.Time
+}
+
+// tokenResponse is the response of the token endpoint, see RFC 6749 section 5.1
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+func newOAuth2Provider(spec model.OAuth2Auth, client *resty.Client) *oauth2Provider {
+	return &oauth2Provider{spec: spec, client: client, now: time.Now, tokens: make(map[string]oauth2Token)}
+}
+
+func (p *oauth2Provider) Authenticate(request *resty.Request, context contexts.RunningContext) error {
+	tokenURL, params := p.tokenRequest(context)
+	key := requestKey(tokenURL, params)
+
+	// The lock is kept while the token is requested, so concurrent requests wait for the same token
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	token, found := p.tokens[key]
+	if !found || (!token.expiry.IsZero() && !p.now().Before(token.expiry)) {
+		var err error
+		if token, err = p.requestToken(request, tokenURL, params); err != nil {
+			return err
+		}
+		p.tokens[key] = token
+	}
+
+	context.Set(p.spec.Token(), token.value)
+	request.SetAuthToken(token.value)
+	return nil
+}
+
+// Renew discards the token of the context, unless it was already renewed by another request rejected with it
+func (p *oauth2Provider) Renew(context contexts.RunningContext) bool {
+	tokenURL, params := p.tokenRequest(context)
+	key := requestKey(tokenURL, params)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	token, found := p.tokens[key]
+	if !found {
+		return false
+	}
+	if token.value == context.Get(p.spec.Token()) {
+		delete(p.tokens, key)
+	}
+	return true
+}
+
+// requestKey identifies a token request, a token is cached for each one
+func requestKey(tokenURL string, params url.Values) string {
+	return tokenURL + "?" + params.Encode()
+}
+
+// tokenRequest returns the URL of the token endpoint and the parameters of the request, evaluated in the context
+func (p *oauth2Provider) tokenRequest(context contexts.RunningContext) (string, url.Values) {
+	eval := evaluators.NewJsonXEvaluator(context)
+
+	params := url.Values{
+		"grant_type": {p.spec.Grant()},
+		"client_id":  {eval.EvaluateStr(p.spec.ClientID)},
+	}
+	if p.spec.ClientSecret != "" {
+		params.Set("client_secret", eval.EvaluateStr(p.spec.ClientSecret))
+	}
+	if p.spec.Grant() == model.GrantPassword {
+		params.Set("username", eval.EvaluateStr(p.spec.Username))
+		params.Set("password", eval.EvaluateStr(p.spec.Password))
+	}
+	if len(p.spec.Scopes) > 0 {
+		params.Set("scope", strings.Join(p.spec.Scopes, " "))
+	}
+
+	return eval.EvaluateStr(p.spec.TokenURL), params
+}
+
+// requestToken requests a new token, with the context of the request to authenticate, so it is cancelled with it
+func (p *oauth2Provider) requestToken(request *resty.Request, tokenURL string, params url.Values) (oauth2Token, error) {
+	response, err := p.client.R().
+		SetContext(request.Context()).
+		SetHeader("Accept", "application/json").
+		SetFormDataFromValues(params).
+		Post(tokenURL)
+
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	if !response.IsSuccess() {
+		return oauth2Token{}, fmt.Errorf("oauth2 token request failed with status %d: %s", response.StatusCode(), response.Body())
+	}
+
+	var tokenResp tokenResponse
+	if err := json.Unmarshal(response.Body(), &tokenResp); err != nil {
+		return oauth2Token{}, fmt.Errorf("oauth2 token response is not valid: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return oauth2Token{}, fmt.Errorf("oauth2 token response has no access_token")
+	}
+
+	token := oauth2Token{value: tokenResp.AccessToken}
+
+	if seconds, err := tokenResp.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		// The token is renewed before it expires, so it does not expire while a request is sent
+		lifetime := time.Duration(seconds) * time.Second
+		token.expiry = p.now().Add(lifetime - lifetime/10)
+	}
+
+	return token, nil
+}
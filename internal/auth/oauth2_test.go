@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/contexts"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+// newTokenServer returns a stub of a token endpoint that issues a new token in each request, and
+// records the parameters of the requests
+func newTokenServer(requests *[]url.Values) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		*requests = append(*requests, r.PostForm)
+
+		if r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", len(*requests)),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+}
+
+func Test_oauth2_provider(t *testing.T) {
+	var tokenRequests []url.Values
+	tokenServer := newTokenServer(&tokenRequests)
+	defer tokenServer.Close()
+
+	var got received
+	server := newApiServer(&got)
+	defer server.Close()
+
+	spec := model.OAuth2Auth{
+		TokenURL:     tokenServer.URL + "/oauth/token",
+		ClientID:     "${clientId}",
+		ClientSecret: "secret",
+		Scopes:       []string{"credits:read", "credits:write"},
+	}
+
+	// GIVEN an OAuth2 provider with a clock that can be moved forward
+	now := time.Now()
+	provider := newOAuth2Provider(spec, resty.New())
+	provider.now = func() time.Time { return now }
+	context := newContext(map[string]any{"clientId": "partner"})
+
+	send := func() {
+		request := resty.New().R()
+		assert.NoError(t, provider.Authenticate(request, context))
+		_, err := request.Get(server.URL)
+		assert.NoError(t, err)
+	}
+
+	// WHEN requests are sent
+	send()
+	send()
+
+	// THEN the token is requested once, and it is visible in the context
+	assert.Equal(t, "Bearer token-1", got.authorization)
+	assert.Equal(t, "token-1", context.Get(model.DefaultTokenName))
+	assert.Equal(t, []url.Values{{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"partner"},
+		"client_secret": {"secret"},
+		"scope":         {"credits:read credits:write"},
+	}}, tokenRequests)
+
+	// WHEN the token is about to expire
+	now = now.Add(55 * time.Minute)
+	send()
+
+	// THEN a new token is requested
+	assert.Equal(t, "Bearer token-2", got.authorization)
+
+	// WHEN the token is renewed, e.g. after a 401 response
+	assert.True(t, provider.Renew(context))
+	send()
+
+	// THEN a new token is requested
+	assert.Equal(t, "Bearer token-3", got.authorization)
+	assert.Len(t, tokenRequests, 3)
+}
+
+func Test_oauth2_provider_errors(t *testing.T) {
+	var tokenRequests []url.Values
+	tokenServer := newTokenServer(&tokenRequests)
+	defer tokenServer.Close()
+
+	provider := newOAuth2Provider(model.OAuth2Auth{
+		TokenURL:     tokenServer.URL,
+		GrantType:    model.GrantPassword,
+		ClientID:     "partner",
+		ClientSecret: "wrong",
+		Username:     "ana",
+		Password:     "pass",
+	}, resty.New())
+
+	context := newContext(nil)
+	err := provider.Authenticate(resty.New().R(), context)
+
+	assert.EqualError(t, err, `oauth2 token request failed with status 401: {"error": "invalid_client"}`)
+	assert.Equal(t, "password", tokenRequests[0].Get("grant_type"))
+	assert.Equal(t, "ana", tokenRequests[0].Get("username"))
+	assert.False(t, provider.Renew(context))
+}
+
+func Test_oauth2_provider_shares_the_tokens_of_each_client(t *testing.T) {
+	// GIVEN a token endpoint that issues a new token for the client in each request
+	var tokens int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token": "%s-%d"}`, r.PostForm.Get("client_id"), atomic.AddInt32(&tokens, 1))
+	}))
+	defer tokenServer.Close()
+
+	// AND a provider shared by several runners
+	providers := NewProviders()
+	spec := &model.Auth{OAuth2: &model.OAuth2Auth{TokenURL: tokenServer.URL, ClientID: "${clientId}"}}
+	provider := providers.Get(spec, resty.New())
+	assert.Same(t, provider, providers.Get(spec, resty.New()))
+	assert.Nil(t, providers.Get(nil, resty.New()))
+
+	// WHEN the runners of two clients authenticate at the same time
+	var wg sync.WaitGroup
+	runContexts := make([]contexts.RunningContext, 10)
+	for i := range runContexts {
+		runContexts[i] = newContext(map[string]any{"clientId": fmt.Sprintf("client%d", i%2)})
+		wg.Add(1)
+		go func(context contexts.RunningContext) {
+			defer wg.Done()
+			assert.NoError(t, provider.Authenticate(resty.New().R(), context))
+		}(runContexts[i])
+	}
+	wg.Wait()
+
+	// THEN a token is requested for each client, and the runners of a client share it
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokens))
+	assert.Equal(t, runContexts[0].Get(model.DefaultTokenName), runContexts[2].Get(model.DefaultTokenName))
+	assert.NotEqual(t, runContexts[0].Get(model.DefaultTokenName), runContexts[1].Get(model.DefaultTokenName))
+
+	// WHEN two runners of a client renew the same token
+	assert.True(t, provider.Renew(runContexts[0]))
+	assert.NoError(t, provider.Authenticate(resty.New().R(), runContexts[0]))
+	assert.True(t, provider.Renew(runContexts[2]))
+	assert.NoError(t, provider.Authenticate(resty.New().R(), runContexts[2]))
+
+	// THEN the token is requested once, and both use the new token
+	assert.Equal(t, int32(3), atomic.LoadInt32(&tokens))
+	assert.Equal(t, runContexts[0].Get(model.DefaultTokenName), runContexts[2].Get(model.DefaultTokenName))
+}
@@ -0,0 +1,86 @@
+// Package bodies decodes response bodies to values that can be compared with the expected body of a step
+package bodies
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+// Format returns the format of a body with the given content type. An empty body has no format
+func Format(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return model.FormatNone
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return model.FormatJSON
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return model.FormatXML
+	case strings.HasPrefix(mediaType, "text/"):
+		return model.FormatText
+	default:
+		return model.FormatBinary
+	}
+}
+
+// Decode returns the body decoded with the given format:
+//   - json: the decoded value, numbers are kept as json.Number so decimals are not rounded
+//   - xml: an object with the root element, see decodeXML
+//   - text: the body as a string
+//   - binary: an object with the "size" and the "sha256" hash of the body
+//   - none: nil, it fails if the body is not empty
+func Decode(format string, body []byte) (any, error) {
+	switch format {
+	case model.FormatJSON:
+		return decodeJSON(body)
+	case model.FormatXML:
+		return decodeXML(body)
+	case model.FormatText:
+		return string(body), nil
+	case model.FormatBinary:
+		return Summary(body), nil
+	case model.FormatNone:
+		if len(body) > 0 {
+			return nil, fmt.Errorf("expected an empty body, received %d bytes", len(body))
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown body format '%s'", format)
+	}
+}
+
+// Summary returns the size and the SHA-256 hash, in hexadecimal, of a binary body
+func Summary(body []byte) map[string]any {
+	hash := sha256.Sum256(body)
+	return map[string]any{
+		"size":   len(body),
+		"sha256": hex.EncodeToString(hash[:]),
+	}
+}
+
+func decodeJSON(body []byte) (any, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,116 @@
+package bodies
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+func Test_format_from_content_type(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{contentType: "application/json; charset=utf-8", body: "[]", want: model.FormatJSON},
+		{contentType: "application/problem+json", body: "{}", want: model.FormatJSON},
+		{contentType: "application/xml", body: "<a/>", want: model.FormatXML},
+		{contentType: "application/atom+xml", body: "<a/>", want: model.FormatXML},
+		{contentType: "text/plain", body: "ok", want: model.FormatText},
+		{contentType: "application/pdf", body: "%PDF", want: model.FormatBinary},
+		{contentType: "", body: "ok", want: model.FormatBinary},
+		{contentType: "application/json", body: "", want: model.FormatNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType+" "+tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Format(tt.contentType, []byte(tt.body)))
+		})
+	}
+}
+
+func Test_decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		body    string
+		want    any
+		wantErr string
+	}{
+		{
+			name:   "json array",
+			format: model.FormatJSON,
+			body:   `[{"id": 1}, {"id": 2.50}]`,
+			want:   []any{map[string]any{"id": json.Number("1")}, map[string]any{"id": json.Number("2.50")}},
+		},
+		{
+			name:   "json scalar",
+			format: model.FormatJSON,
+			body:   `"approved"`,
+			want:   "approved",
+		},
+		{
+			name:   "empty json",
+			format: model.FormatJSON,
+			body:   "",
+			want:   nil,
+		},
+		{
+			name:   "xml",
+			format: model.FormatXML,
+			body: `<?xml version="1.0"?>
+				<credit xmlns="urn:credits" id="7">
+					<status>approved</status>
+					<item sku="a">Phone</item>
+					<item sku="b">Case</item>
+					<notes/>
+				</credit>`,
+			want: map[string]any{"credit": map[string]any{
+				"@id":    "7",
+				"status": "approved",
+				"item": []any{
+					map[string]any{"@sku": "a", "#text": "Phone"},
+					map[string]any{"@sku": "b", "#text": "Case"},
+				},
+				"notes": "",
+			}},
+		},
+		{
+			name:    "invalid xml",
+			format:  model.FormatXML,
+			body:    "<credit>",
+			wantErr: "invalid XML body: XML syntax error on line 1: unexpected EOF",
+		},
+		{
+			name:   "text",
+			format: model.FormatText,
+			body:   "OK",
+			want:   "OK",
+		},
+		{
+			name:   "binary",
+			format: model.FormatBinary,
+			body:   "abc",
+			want:   map[string]any{"size": 3, "sha256": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		},
+		{
+			name:    "none with a body",
+			format:  model.FormatNone,
+			body:    "OK",
+			wantErr: "expected an empty body, received 2 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.format, []byte(tt.body))
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package bodies
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// attributePrefix is added to the names of the attributes, so they do not clash with child elements
+	attributePrefix = "@"
+	// textKey is the name of the text of an element with attributes or child elements
+	textKey = "#text"
+)
+
+// decodeXML converts an XML document to an object with the root element. An element with only text is
+// its text. Other elements are objects with their attributes, prefixed with "@", their child elements,
+// and their text as "#text". Repeated child elements are a list. Namespaces are ignored.
+//
+//	<credit id="7"><status>approved</status></credit>
+//
+// is decoded as
+//
+//	{"credit": {"@id": "7", "status": "approved"}}
+func decodeXML(body []byte) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid XML body: no root element")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML body: %w", err)
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := decodeElement(decoder, start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid XML body: %w", err)
+			}
+			return map[string]any{start.Name.Local: value}, nil
+		}
+	}
+}
+
+func decodeElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	element := make(map[string]any)
+	var text strings.Builder
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		element[attributePrefix+attr.Name.Local] = attr.Value
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			addChild(element, t.Name.Local, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(element) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				element[textKey] = content
+			}
+			return element, nil
+		}
+	}
+}
+
+// addChild adds a child element, the repeated elements are collected in a list
+func addChild(element map[string]any, name string, child any) {
+	existing, found := element[name]
+	if !found {
+		element[name] = child
+		return
+	}
+
+	if list, isList := existing.([]any); isList {
+		element[name] = append(list, child)
+	} else {
+		element[name] = []any{existing, child}
+	}
+}
@@ -0,0 +1,15 @@
+package maps
+
+import "sort"
+
+// SortedKeys returns the keys of the map in ascending order
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -10,6 +10,8 @@ import (
 type RunningContext interface {
 	Set(name string, expression model.AnyValue)
 	Get(name string) interface{}
+	// Values returns a copy of the values of the context
+	Values() map[string]model.AnyValue
 }
 
 type runningContext struct {
@@ -34,3 +36,67 @@ func (c runningContext) Set(name string, expression model.AnyValue) {
 func (c runningContext) Get(name string) interface{} {
 	return c.entries[name]
 }
+
+func (c runningContext) Values() map[string]model.AnyValue {
+	values := make(map[string]model.AnyValue, len(c.entries))
+	for name, value := range c.entries {
+		values[name] = value
+	}
+	return values
+}
+
+// ScopedContext has its own values, and reads the other values from its parent. Values set as local
+// are only visible in the scope. Other values are set in the parent, unless the scope is isolated.
+type ScopedContext struct {
+	RunningContext
+	local    map[string]model.AnyValue
+	isolated bool
+}
+
+// NewScopedContext returns a context with a scope for local values on top of the parent context
+func NewScopedContext(parent RunningContext) *ScopedContext {
+	return &ScopedContext{parent, make(map[string]model.AnyValue), false}
+}
+
+// NewIsolatedContext returns a context that reads values from the parent context, but all the values
+// set in it are only visible in the scope
+func NewIsolatedContext(parent RunningContext) *ScopedContext {
+	return &ScopedContext{parent, make(map[string]model.AnyValue), true}
+}
+
+// Set sets the value of a variable in the parent context, or in the scope if it is isolated
+func (c *ScopedContext) Set(name string, expression model.AnyValue) {
+	if c.isolated {
+		c.local[name] = expression
+		return
+	}
+	c.RunningContext.Set(name, expression)
+}
+
+// SetLocal sets the value of a variable only visible in the scope
+func (c *ScopedContext) SetLocal(name string, expression model.AnyValue) {
+	c.local[name] = expression
+}
+
+// Values returns the values of the parent context, replaced by the values of the scope
+func (c *ScopedContext) Values() map[string]model.AnyValue {
+	values := c.RunningContext.Values()
+	for name, value := range c.local {
+		values[name] = value
+	}
+	return values
+}
+
+// Get returns the value of a variable in the scope, or in the parent context if not defined in the scope
+func (c *ScopedContext) Get(name string) interface{} {
+	if value, found := c.local[name]; found {
+		return value
+	}
+	return c.RunningContext.Get(name)
+}
+
+// GetLocal returns the value of a variable set in the scope, and whether it is set. The parent context is not read
+func (c *ScopedContext) GetLocal(name string) (interface{}, bool) {
+	value, found := c.local[name]
+	return value, found
+}
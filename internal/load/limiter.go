@@ -0,0 +1,38 @@
+package load
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter spaces the requests to keep a target rate of requests per second
+type limiter struct {
+	lock     sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// Wait blocks until the next request can be sent
+func (l *limiter) Wait() {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	slot := l.next
+	l.next = l.next.Add(l.interval)
+	l.lock.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
@@ -0,0 +1,112 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/internal/auth"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"github.com/totemcaf/test-by-example.git/internal/runners"
+	"go.uber.org/zap"
+)
+
+// Config defines the load to generate
+type Config struct {
+	// Users is the number of virtual users running the flows at the same time
+	Users int
+	// RampUp is the time to start all the virtual users, they are started at regular intervals
+	RampUp time.Duration
+	// Duration is the total time of the test, including the ramp-up. Zero is no time limit
+	Duration time.Duration
+	// Repetitions is the number of times each virtual user runs the flows. Zero is no limit
+	Repetitions int
+	// Rate is the target of requests per second of all the virtual users. Zero is no limit
+	Rate float64
+	// Timeout limits the time of the requests of the steps without timeout. Zero is no limit
+	Timeout time.Duration
+	// TLS is the global TLS configuration of the requests, nil to use the defaults
+	TLS *model.TLS
+}
+
+func (c Config) Validate() error {
+	if c.Users < 1 {
+		return fmt.Errorf("users must be at least 1")
+	}
+	if c.RampUp < 0 || c.Duration < 0 || c.Repetitions < 0 || c.Rate < 0 || c.Timeout < 0 {
+		return fmt.Errorf("ramp-up, duration, repetitions, rate and timeout cannot be negative")
+	}
+	if c.Duration == 0 && c.Repetitions == 0 {
+		return fmt.Errorf("a duration or a number of repetitions is required")
+	}
+	return nil
+}
+
+// Run starts the virtual users, each one runs the flows in order with its own context, until the
+// duration or the repetitions are reached. The flows running when the duration is reached are interrupted,
+// and they are not counted, as their last requests were cut. It returns the statistics of the executed steps.
+func Run(config Config, flows []*model.TestFlow, logger *zap.SugaredLogger) *Stats {
+	stats := newStats()
+	limiter := newLimiter(config.Rate)
+	// The virtual users share the auth providers, so they reuse the credentials obtained, e.g. OAuth2 tokens
+	authProviders := auth.NewProviders()
+	start := time.Now()
+
+	ctx := context.Background()
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(config.Duration))
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	for user := 0; user < config.Users; user++ {
+		delay := config.RampUp * time.Duration(user) / time.Duration(config.Users)
+
+		wg.Add(1)
+		go func(user int) {
+			defer wg.Done()
+			time.Sleep(delay)
+			logger.Debugf("Starting virtual user %d", user+1)
+			runUser(ctx, config, flows, limiter, authProviders, stats, logger)
+		}(user)
+	}
+	wg.Wait()
+
+	stats.Duration = time.Since(start)
+	return stats
+}
+
+func runUser(ctx context.Context, config Config, flows []*model.TestFlow, limiter *limiter, authProviders *auth.Providers, stats *Stats, logger *zap.SugaredLogger) {
+	for repetition := 1; config.Repetitions == 0 || repetition <= config.Repetitions; repetition++ {
+		for _, flow := range flows {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// New runners for each iteration, so values are generated again, with the credentials of the auth providers
+			options := []runners.Option{
+				runners.WithOutput(io.Discard),
+				runners.WithContext(ctx),
+				runners.WithBeforeRequest(limiter.Wait),
+				runners.WithTimeout(config.Timeout),
+				runners.WithTLS(config.TLS),
+				runners.WithAuthProviders(authProviders),
+			}
+
+			for _, runner := range runners.NewTestRunners(flow, logger, options...) {
+				result, err := runner.Run()
+				if ctx.Err() != nil {
+					logger.Debugf("Flow %s interrupted by the end of the test", result.Name)
+					return
+				}
+				if err != nil {
+					logger.Debugf("Flow %s failed: %s", result.Name, err.Error())
+				}
+				stats.Add(result)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package load
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"github.com/totemcaf/test-by-example.git/internal/results"
+	"go.uber.org/zap"
+)
+
+func asPointer[T any](t T) *T {
+	return &t
+}
+
+func Test_run_repeats_the_flows_for_each_virtual_user(t *testing.T) {
+	// GIVEN a server that counts the requests
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	flow := &model.TestFlow{
+		Metadata: model.Metadata{Name: "ping"},
+		Spec: model.TestFlowSpec{Steps: []model.StepSpec{{
+			Name:     asPointer("get"),
+			Get:      asPointer(server.URL),
+			Response: &model.Response{StatusCode: 200, Body: &model.Json{}},
+		}}},
+	}
+
+	// WHEN 3 users run the flow 4 times
+	stats := Run(Config{Users: 3, Repetitions: 4}, []*model.TestFlow{flow}, zap.NewNop().Sugar())
+
+	// THEN all the requests are sent and recorded
+	assert.Equal(t, int32(12), atomic.LoadInt32(&requests))
+	assert.Equal(t, 12, stats.Iterations)
+	assert.Equal(t, 0, stats.FailedFlows)
+	assert.Len(t, stats.Steps(), 1)
+	assert.Equal(t, "ping/get", stats.Steps()[0].Name)
+	assert.Equal(t, 12, stats.Requests())
+	assert.Equal(t, 0, stats.Errors())
+}
+
+func Test_run_shares_the_oauth2_tokens_between_virtual_users(t *testing.T) {
+	// GIVEN a token endpoint that counts the tokens issued, and a server that requires the token
+	var tokens int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokens, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "t0k3n", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	flow := &model.TestFlow{
+		Metadata: model.Metadata{Name: "ping"},
+		Spec: model.TestFlowSpec{
+			Auth: &model.Auth{OAuth2: &model.OAuth2Auth{TokenURL: tokenServer.URL, ClientID: "partner"}},
+			Steps: []model.StepSpec{{
+				Name:     asPointer("get"),
+				Get:      asPointer(server.URL),
+				Response: &model.Response{StatusCode: 200},
+			}},
+		},
+	}
+
+	// WHEN 3 users run the flow 4 times
+	stats := Run(Config{Users: 3, Repetitions: 4}, []*model.TestFlow{flow}, zap.NewNop().Sugar())
+
+	// THEN the token is requested once, and used by all the iterations
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokens))
+	assert.Equal(t, 12, stats.Iterations)
+	assert.Equal(t, 0, stats.FailedFlows)
+}
+
+func Test_run_interrupts_the_flows_at_the_end_of_the_duration(t *testing.T) {
+	// GIVEN a server that responds after a second, unless the request is cancelled
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	flow := &model.TestFlow{
+		Metadata: model.Metadata{Name: "slow"},
+		Spec: model.TestFlowSpec{Steps: []model.StepSpec{{
+			Name:     asPointer("get"),
+			Get:      asPointer(server.URL),
+			Response: &model.Response{StatusCode: 200},
+		}}},
+	}
+
+	// WHEN a user runs the flow for 100 ms
+	start := time.Now()
+	stats := Run(Config{Users: 1, Duration: 100 * time.Millisecond}, []*model.TestFlow{flow}, zap.NewNop().Sugar())
+
+	// THEN the request is interrupted at the end of the duration, and the interrupted flow is not counted
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, 0, stats.Iterations)
+	assert.Equal(t, 0, stats.Requests())
+}
+
+func Test_config_validation(t *testing.T) {
+	assert.NoError(t, Config{Users: 1, Repetitions: 1}.Validate())
+	assert.NoError(t, Config{Users: 1, Duration: time.Second}.Validate())
+	assert.Error(t, Config{Users: 0, Repetitions: 1}.Validate())
+	assert.Error(t, Config{Users: 1}.Validate())
+	assert.Error(t, Config{Users: 1, Repetitions: 1, Rate: -1}.Validate())
+}
+
+func Test_stats_percentiles_and_error_rate(t *testing.T) {
+	// GIVEN 10 executions of a step, with latencies from 1 to 10 ms, and 2 failures
+	stats := newStats()
+	for i := 1; i <= 10; i++ {
+		status := results.Passed
+		attempt := &results.Attempt{Duration: time.Duration(i) * time.Millisecond}
+		if i > 8 {
+			status = results.Failed
+			attempt.Error = "status: different"
+		}
+		stats.Add(&results.FlowResult{
+			Name:   "flow",
+			Status: status,
+			Steps: []*results.StepResult{
+				{Name: "step", Status: status, Attempts: []*results.Attempt{attempt}},
+				{Name: "skipped", Status: results.Skipped},
+			},
+		})
+	}
+
+	// THEN the skipped steps are not counted, and the percentiles are computed
+	assert.Len(t, stats.Steps(), 1)
+	step := stats.Steps()[0]
+	assert.Equal(t, 10, step.Requests)
+	assert.Equal(t, 2, step.Errors)
+	assert.Equal(t, 0.2, step.ErrorRate())
+	assert.Equal(t, 5*time.Millisecond, step.Percentile(50))
+	assert.Equal(t, 9*time.Millisecond, step.Percentile(90))
+	assert.Equal(t, 10*time.Millisecond, step.Percentile(99))
+	assert.Equal(t, 2, stats.FailedFlows)
+}
+
+func Test_stats_count_the_requests_of_retries_and_flow_steps(t *testing.T) {
+	// GIVEN a step retried once, and a flow step with a nested step
+	stats := newStats()
+	stats.Add(&results.FlowResult{
+		Name:   "flow",
+		Status: results.Passed,
+		Steps: []*results.StepResult{
+			{Name: "create", Status: results.Passed, Attempts: []*results.Attempt{
+				{Duration: time.Millisecond, StatusCode: 503, Error: "received status 503"},
+				{Duration: 2 * time.Millisecond, StatusCode: 201},
+			}},
+			{Name: "login", Status: results.Passed, Steps: []*results.StepResult{
+				{Name: "get-token", Status: results.Passed, Attempts: []*results.Attempt{{Duration: time.Millisecond, StatusCode: 200}}},
+			}},
+		},
+	})
+
+	// THEN each attempt is a request, and the nested steps are named after the flow step
+	assert.Len(t, stats.Steps(), 2)
+	assert.Equal(t, []string{"flow/create", "flow/login / get-token"}, []string{stats.Steps()[0].Name, stats.Steps()[1].Name})
+	assert.Equal(t, 2, stats.Steps()[0].Requests)
+	assert.Equal(t, 1, stats.Steps()[0].Errors)
+	assert.Equal(t, 1, stats.Steps()[1].Requests)
+	assert.Equal(t, 3, stats.Requests())
+	assert.Equal(t, 1, stats.Errors())
+}
+
+func Test_limiter_spaces_requests(t *testing.T) {
+	// GIVEN a limiter of 100 requests per second
+	limiter := newLimiter(100)
+
+	// WHEN 11 requests are sent
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		limiter.Wait()
+	}
+
+	// THEN they take at least 100 ms
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
@@ -0,0 +1,168 @@
+package load
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/internal/results"
+)
+
+// percentiles are the latency percentiles reported for each step
+var percentiles = []float64{50, 90, 95, 99}
+
+// StepStats are the latencies and errors of the executions of a step
+type StepStats struct {
+	Name      string
+	Requests  int
+	Errors    int
+	latencies []time.Duration
+}
+
+// ErrorRate returns the fraction of the requests that failed
+func (s *StepStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// Percentile returns the latency below which the given percentage of the requests are
+func (s *StepStats) Percentile(percentage float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(s.latencies))
+	copy(sorted, s.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	index := int(float64(len(sorted))*percentage/100+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// Stats aggregates the results of the flows executed by the virtual users
+type Stats struct {
+	lock        sync.Mutex
+	Duration    time.Duration
+	Iterations  int
+	FailedFlows int
+	steps       map[string]*StepStats
+	stepNames   []string
+}
+
+func newStats() *Stats {
+	return &Stats{steps: make(map[string]*StepStats)}
+}
+
+// Add records the requests of the steps of a flow execution, including the retries and polls of each step, and
+// the steps of the flows run as steps. Steps that sent no request, like the skipped ones, are not counted
+func (s *Stats) Add(flow *results.FlowResult) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Iterations++
+	if flow.Status == results.Failed {
+		s.FailedFlows++
+	}
+
+	s.addSteps(flow.Name+"/", flow.Steps)
+}
+
+// addSteps records the attempts of each step, and of its nested steps named after it, e.g. "flow/login / get-token"
+func (s *Stats) addSteps(prefix string, steps []*results.StepResult) {
+	for _, step := range steps {
+		name := prefix + step.Name
+
+		if len(step.Attempts) > 0 {
+			stats, found := s.steps[name]
+			if !found {
+				stats = &StepStats{Name: name}
+				s.steps[name] = stats
+				s.stepNames = append(s.stepNames, name)
+			}
+
+			for _, attempt := range step.Attempts {
+				stats.Requests++
+				stats.latencies = append(stats.latencies, attempt.Duration)
+				if attempt.Error != "" {
+					stats.Errors++
+				}
+			}
+		}
+
+		s.addSteps(name+" / ", step.Steps)
+	}
+}
+
+// Steps returns the statistics of each step, in the order they were first executed
+func (s *Stats) Steps() []*StepStats {
+	steps := make([]*StepStats, len(s.stepNames))
+	for i, name := range s.stepNames {
+		steps[i] = s.steps[name]
+	}
+	return steps
+}
+
+// Requests returns the number of requests sent
+func (s *Stats) Requests() int {
+	requests := 0
+	for _, step := range s.steps {
+		requests += step.Requests
+	}
+	return requests
+}
+
+// Errors returns the number of failed requests
+func (s *Stats) Errors() int {
+	errors := 0
+	for _, step := range s.steps {
+		errors += step.Errors
+	}
+	return errors
+}
+
+// Throughput returns the requests per second
+func (s *Stats) Throughput() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Requests()) / s.Duration.Seconds()
+}
+
+// Write prints a table with the latency percentiles and error rate of each step, followed by the totals
+func (s *Stats) Write(writer io.Writer) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprint(table, "STEP\tREQUESTS\tERRORS\tERROR RATE")
+	for _, percentile := range percentiles {
+		_, _ = fmt.Fprintf(table, "\tP%g", percentile)
+	}
+	_, _ = fmt.Fprintln(table)
+
+	for _, step := range s.Steps() {
+		_, _ = fmt.Fprintf(table, "%s\t%d\t%d\t%.2f%%", step.Name, step.Requests, step.Errors, step.ErrorRate()*100)
+		for _, percentile := range percentiles {
+			_, _ = fmt.Fprintf(table, "\t%s", step.Percentile(percentile).Round(time.Millisecond))
+		}
+		_, _ = fmt.Fprintln(table)
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(writer, "\n%d iterations, %d failed, %d requests, %d errors in %s (%.2f requests/s)\n",
+		s.Iterations, s.FailedFlows, s.Requests(), s.Errors(), s.Duration.Round(time.Millisecond), s.Throughput())
+
+	return err
+}
@@ -0,0 +1,126 @@
+package model
+
+import "fmt"
+
+const (
+	// ApiKeyInHeader sends the API key in a request header
+	ApiKeyInHeader = "header"
+	// ApiKeyInQuery sends the API key in a query parameter
+	ApiKeyInQuery = "query"
+
+	// GrantClientCredentials requests OAuth2 tokens with the client id and secret
+	GrantClientCredentials = "client_credentials"
+	// GrantPassword requests OAuth2 tokens with the credentials of a user
+	GrantPassword = "password"
+
+	// DefaultTokenName is the name of the value with the OAuth2 access token
+	DefaultTokenName = "accessToken"
+)
+
+// Auth authenticates the requests of the steps of a flow. Only one of the providers can be set.
+// The values can have expressions, they are evaluated when the request is sent
+type Auth struct {
+	Basic  *BasicAuth  `yaml:"basic,omitempty"`
+	Bearer *BearerAuth `yaml:"bearer,omitempty"`
+	ApiKey *ApiKeyAuth `yaml:"apiKey,omitempty"`
+	OAuth2 *OAuth2Auth `yaml:"oauth2,omitempty"`
+}
+
+// BasicAuth sends the user and password in the Authorization header
+type BasicAuth struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// BearerAuth sends a static token in the Authorization header
+type BearerAuth struct {
+	Token string `yaml:"token"`
+}
+
+// ApiKeyAuth sends a key in a header or in a query parameter
+type ApiKeyAuth struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+	// In is "header" or "query", the default is "header"
+	In string `yaml:"in,omitempty"`
+}
+
+// OAuth2Auth requests an access token to a token endpoint, and sends it as a bearer token. The token is
+// renewed when it expires, or when a request is rejected with 401 Unauthorized
+type OAuth2Auth struct {
+	TokenURL string `yaml:"tokenUrl"`
+	// GrantType is "client_credentials" or "password", the default is "client_credentials"
+	GrantType    string   `yaml:"grantType,omitempty"`
+	ClientID     string   `yaml:"clientId"`
+	ClientSecret string   `yaml:"clientSecret,omitempty"`
+	Username     string   `yaml:"username,omitempty"`
+	Password     string   `yaml:"password,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	// TokenName is the name of the value with the access token, so it can be used in expressions.
+	// The default is "accessToken"
+	TokenName string `yaml:"tokenName,omitempty"`
+}
+
+func (a *Auth) Validate() error {
+	if a == nil {
+		return nil
+	}
+
+	if countSet(a.Basic != nil, a.Bearer != nil, a.ApiKey != nil, a.OAuth2 != nil) != 1 {
+		return fmt.Errorf("auth requires one of basic, bearer, apiKey or oauth2")
+	}
+
+	switch {
+	case a.Basic != nil && a.Basic.Username == "":
+		return fmt.Errorf("basic auth requires a username")
+	case a.Bearer != nil && a.Bearer.Token == "":
+		return fmt.Errorf("bearer auth requires a token")
+	case a.ApiKey != nil:
+		return a.ApiKey.Validate()
+	case a.OAuth2 != nil:
+		return a.OAuth2.Validate()
+	}
+	return nil
+}
+
+func (a ApiKeyAuth) Validate() error {
+	if a.Name == "" || a.Value == "" {
+		return fmt.Errorf("apiKey auth requires a name and a value")
+	}
+	if a.In != "" && a.In != ApiKeyInHeader && a.In != ApiKeyInQuery {
+		return fmt.Errorf("invalid apiKey in '%s', expected '%s' or '%s'", a.In, ApiKeyInHeader, ApiKeyInQuery)
+	}
+	return nil
+}
+
+func (o OAuth2Auth) Validate() error {
+	if o.TokenURL == "" || o.ClientID == "" {
+		return fmt.Errorf("oauth2 auth requires a tokenUrl and a clientId")
+	}
+	switch o.GrantType {
+	case "", GrantClientCredentials:
+	case GrantPassword:
+		if o.Username == "" {
+			return fmt.Errorf("oauth2 password grant requires a username")
+		}
+	default:
+		return fmt.Errorf("invalid oauth2 grantType '%s', expected '%s' or '%s'", o.GrantType, GrantClientCredentials, GrantPassword)
+	}
+	return nil
+}
+
+// Grant returns the grant type used to request the tokens
+func (o OAuth2Auth) Grant() string {
+	if o.GrantType == "" {
+		return GrantClientCredentials
+	}
+	return o.GrantType
+}
+
+// Token returns the name of the value with the access token
+func (o OAuth2Auth) Token() string {
+	if o.TokenName == "" {
+		return DefaultTokenName
+	}
+	return o.TokenName
+}
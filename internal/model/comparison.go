@@ -0,0 +1,73 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/totemcaf/test-by-example.git/pkg/jsonx"
+)
+
+// Comparison configures how the response body is compared with the expected body
+type Comparison struct {
+	// Mode is "exact" to report values in the response that are not expected, or "contains"
+	// to only check the expected values
+	Mode string `yaml:"mode,omitempty"`
+	// Numbers is "numeric" to compare numbers by value, or "exact" to also check integers are
+	// not compared with floats
+	Numbers   string     `yaml:"numbers,omitempty"`
+	Tolerance *Tolerance `yaml:"tolerance,omitempty"`
+}
+
+// Tolerance is the accepted difference between numbers, the relative tolerance is a fraction
+// of the expected value
+type Tolerance struct {
+	Absolute float64 `yaml:"absolute,omitempty"`
+	Relative float64 `yaml:"relative,omitempty"`
+}
+
+func (c Comparison) Validate() error {
+	if c.Mode != "" {
+		if _, err := jsonx.ParseMatchMode(c.Mode); err != nil {
+			return err
+		}
+	}
+	if c.Numbers != "" {
+		if _, err := jsonx.ParseNumberMode(c.Numbers); err != nil {
+			return err
+		}
+	}
+	if c.Tolerance != nil && (c.Tolerance.Absolute < 0 || c.Tolerance.Relative < 0) {
+		return fmt.Errorf("tolerance cannot be negative")
+	}
+	return nil
+}
+
+// Or returns this comparison with the values not set taken from defaults
+func (c Comparison) Or(defaults Comparison) Comparison {
+	if c.Mode == "" {
+		c.Mode = defaults.Mode
+	}
+	if c.Numbers == "" {
+		c.Numbers = defaults.Numbers
+	}
+	if c.Tolerance == nil {
+		c.Tolerance = defaults.Tolerance
+	}
+	return c
+}
+
+// DiffOptions returns the options to compare values with jsonx
+func (c Comparison) DiffOptions() []jsonx.DiffOption {
+	var options []jsonx.DiffOption
+
+	if c.Mode != "" {
+		options = append(options, jsonx.WithMode(jsonx.MatchMode(c.Mode)))
+	}
+	if c.Numbers != "" {
+		options = append(options, jsonx.WithNumbers(jsonx.NumberMode(c.Numbers)))
+	}
+	if c.Tolerance != nil {
+		options = append(options, jsonx.WithTolerance(c.Tolerance.Absolute, c.Tolerance.Relative))
+	}
+
+	return options
+}
@@ -0,0 +1,40 @@
+package model
+
+import "fmt"
+
+// Examples are the rows of values used to run a flow or a step once per row. The rows are listed
+// in the spec, or read from a CSV, JSON or YAML file
+type Examples struct {
+	Rows []map[string]any
+	// File is the path of the file with the rows, relative to the spec file
+	File string
+}
+
+// UnmarshalYAML reads the examples as a list of rows, or as the path of the file with the rows
+func (e *Examples) UnmarshalYAML(unmarshal func(any) error) error {
+	var file string
+	if err := unmarshal(&file); err == nil {
+		e.File = file
+		return nil
+	}
+
+	return unmarshal(&e.Rows)
+}
+
+func (e *Examples) Validate() error {
+	if e == nil {
+		return nil
+	}
+	if len(e.Rows) == 0 {
+		if e.File != "" {
+			return fmt.Errorf("no examples found in %s", e.File)
+		}
+		return fmt.Errorf("examples cannot be empty")
+	}
+	return nil
+}
+
+// ExampleName returns the name of the flow or step run with the example in the given position, starting at 1
+func ExampleName(name string, index int) string {
+	return fmt.Sprintf("%s [example %d]", name, index)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/totemcaf/test-by-example.git/pkg/jsonx"
+)
+
+// Part is a part of a multipart request body, with a value or the content of a file
+type Part struct {
+	Name  string `yaml:"name"`
+	Value any    `yaml:"value,omitempty"`
+	// File is the path of the file to send, relative to the spec file
+	File string `yaml:"file,omitempty"`
+	// FileName is the name of the file sent in the part, by default the name of the file
+	FileName string `yaml:"fileName,omitempty"`
+	// ContentType is the content type of the file, by default it is detected from its content
+	ContentType string `yaml:"contentType,omitempty"`
+	// dir is the folder of the spec file, relative file paths are resolved from it
+	dir string
+}
+
+func (p Part) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("a multipart part requires a name")
+	}
+	if (p.Value == nil) == (p.File == "") {
+		return fmt.Errorf("part '%s' requires a value or a file", p.Name)
+	}
+	if err := jsonx.Validate(p.Value); err != nil {
+		return fmt.Errorf("part '%s': invalid value: %w", p.Name, err)
+	}
+	return nil
+}
+
+// SetDir sets the folder of the spec file the part was read from
+func (p *Part) SetDir(dir string) {
+	p.dir = dir
+}
+
+// Path returns the path of the given file, relative to the folder of the spec file if it is not absolute
+func (p Part) Path(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(p.dir, file)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+const defaultPollInterval = time.Second
+
+// Poll repeats the request of a step until the response matches the expected one, or the timeout expires.
+// The time between requests is multiplied by the backoff after each request
+type Poll struct {
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Backoff  float64       `yaml:"backoff,omitempty"`
+}
+
+func (p *Poll) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Timeout <= 0 {
+		return fmt.Errorf("poll timeout must be positive")
+	}
+	if p.Interval < 0 {
+		return fmt.Errorf("poll interval cannot be negative")
+	}
+	if p.Backoff != 0 && p.Backoff < 1 {
+		return fmt.Errorf("poll backoff must be at least 1")
+	}
+	return nil
+}
+
+// FirstInterval returns the time to wait before the second request
+func (p *Poll) FirstInterval() time.Duration {
+	if p.Interval == 0 {
+		return defaultPollInterval
+	}
+	return p.Interval
+}
+
+// NextInterval returns the time to wait after waiting the given interval
+func (p *Poll) NextInterval(interval time.Duration) time.Duration {
+	if p.Backoff == 0 {
+		return interval
+	}
+	return time.Duration(float64(interval) * p.Backoff)
+}
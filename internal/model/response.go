@@ -1,6 +1,56 @@
 package model
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/pkg/jsonx"
+)
+
+// Formats of the response body, they define how the body is decoded to compare it with the expected body
+const (
+	// FormatJSON decodes the body as JSON, it can be an object, an array or a scalar
+	FormatJSON = "json"
+	// FormatXML converts the XML elements to objects, see bodies.Decode
+	FormatXML = "xml"
+	// FormatText compares the body as a string
+	FormatText = "text"
+	// FormatBinary compares the size and the SHA-256 hash of the body
+	FormatBinary = "binary"
+	// FormatNone expects an empty body
+	FormatNone = "none"
+)
+
 type Response struct {
 	StatusCode int `yaml:"statusCode"`
-	Body       *Json
+	// Headers are the expected response headers, other headers in the response are not checked
+	Headers Headers `yaml:"headers,omitempty"`
+	// Cookies are the expected response cookies, other cookies in the response are not checked
+	Cookies map[string]string `yaml:"cookies,omitempty"`
+	// Body is the expected body, an object, an array or a scalar. Without body, the response body is not checked
+	Body any `yaml:"body,omitempty"`
+	// Format is how the response body is decoded. By default, it is taken from the Content-Type of the response
+	Format string `yaml:"format,omitempty"`
+	// MaxDuration is the maximum time to receive the response, a slower response fails the step
+	MaxDuration time.Duration `yaml:"maxDuration,omitempty"`
+	Comparison  `yaml:",inline"`
+}
+
+func (r Response) Validate() error {
+	if r.MaxDuration < 0 {
+		return fmt.Errorf("maxDuration cannot be negative")
+	}
+	switch r.Format {
+	case "", FormatJSON, FormatXML, FormatText, FormatBinary:
+	case FormatNone:
+		if r.Body != nil {
+			return fmt.Errorf("a response with format '%s' cannot have a body", FormatNone)
+		}
+	default:
+		return fmt.Errorf("invalid format '%s', expected one of %s, %s, %s, %s or %s", r.Format, FormatJSON, FormatXML, FormatText, FormatBinary, FormatNone)
+	}
+	if err := jsonx.Validate(r.Body); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+	return r.Comparison.Validate()
 }
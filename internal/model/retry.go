@@ -0,0 +1,123 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// RetryConnection retries requests that fail because the connection is refused, reset or closed
+	RetryConnection = "connection"
+	// RetryTimeout retries requests that fail because the connection or the response timed out
+	RetryTimeout = "timeout"
+)
+
+// DefaultRetry retries the idempotent requests that fail by network errors or with a gateway error
+var DefaultRetry = Retry{
+	Attempts:    3,
+	Interval:    100 * time.Millisecond,
+	Backoff:     2,
+	StatusCodes: []int{502, 503, 504},
+	Errors:      []string{RetryConnection, RetryTimeout},
+	Methods:     []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"},
+}
+
+// Retry sends the request of a step again when it fails by a network error, or the response has
+// a status code that shows a temporary failure
+type Retry struct {
+	// Attempts is the maximum number of requests sent, 1 disables the retries
+	Attempts int `yaml:"attempts,omitempty"`
+	// Interval is the time to wait before the first retry, it is multiplied by the backoff after each retry
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Backoff  float64       `yaml:"backoff,omitempty"`
+	// StatusCodes are the status codes retried, unless they are the expected status code
+	StatusCodes []int `yaml:"statusCodes,omitempty"`
+	// Errors are the network errors retried, "connection" or "timeout"
+	Errors []string `yaml:"errors,omitempty"`
+	// Methods are the HTTP methods retried
+	Methods []string `yaml:"methods,omitempty"`
+}
+
+func (r Retry) Validate() error {
+	if r.Attempts < 0 {
+		return fmt.Errorf("retry attempts cannot be negative")
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("retry interval cannot be negative")
+	}
+	if r.Backoff != 0 && r.Backoff < 1 {
+		return fmt.Errorf("retry backoff must be at least 1")
+	}
+	for _, kind := range r.Errors {
+		if kind != RetryConnection && kind != RetryTimeout {
+			return fmt.Errorf("invalid retry error '%s', expected '%s' or '%s'", kind, RetryConnection, RetryTimeout)
+		}
+	}
+	return nil
+}
+
+// Or returns this retry with the values not set taken from defaults. An empty list, e.g. "statusCodes: []",
+// is set, so nothing is retried by that condition
+func (r Retry) Or(defaults Retry) Retry {
+	if r.Attempts == 0 {
+		r.Attempts = defaults.Attempts
+	}
+	if r.Interval == 0 {
+		r.Interval = defaults.Interval
+	}
+	if r.Backoff == 0 {
+		r.Backoff = defaults.Backoff
+	}
+	if r.StatusCodes == nil {
+		r.StatusCodes = defaults.StatusCodes
+	}
+	if r.Errors == nil {
+		r.Errors = defaults.Errors
+	}
+	if r.Methods == nil {
+		r.Methods = defaults.Methods
+	}
+	return r
+}
+
+// Retries returns true if requests with the given method are retried
+func (r Retry) Retries(method string) bool {
+	if r.Attempts < 2 {
+		return false
+	}
+	for _, m := range r.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetriesStatus returns true if responses with the given status code are retried
+func (r Retry) RetriesStatus(statusCode int) bool {
+	for _, code := range r.StatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// RetriesError returns true if the network errors of the given kind are retried
+func (r Retry) RetriesError(kind string) bool {
+	for _, k := range r.Errors {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// NextInterval returns the time to wait after waiting the given interval
+func (r Retry) NextInterval(interval time.Duration) time.Duration {
+	if r.Backoff == 0 {
+		return interval
+	}
+	return time.Duration(float64(interval) * r.Backoff)
+}
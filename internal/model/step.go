@@ -1,6 +1,13 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/pkg/jsonx"
+)
 
 type Headers map[string]string
 
@@ -9,6 +16,7 @@ type Step struct {
 	Kind       string   `yaml:"kind" description:"Kind of API"`
 	Metadata   Metadata `yaml:"metadata"`
 	Spec       StepSpec `yaml:"spec"`
+	sourceFile string
 }
 
 func (s Step) FullName() string {
@@ -24,70 +32,266 @@ func (s Step) Validate() error {
 		return err
 	}
 
-	return nil
+	return s.Spec.Validate()
+}
+
+// SourceFile returns the file the step was read from
+func (s *Step) SourceFile() string {
+	return s.sourceFile
 }
 
+func (s *Step) SetSourceFile(sourceFile string) {
+	s.sourceFile = sourceFile
+}
+
+const (
+	// ContextShared runs a flow step with the context of the calling flow
+	ContextShared = "shared"
+	// ContextIsolated runs a flow step in its own scope
+	ContextIsolated = "isolated"
+)
+
 type StepSpec struct {
-	Get      *string
-	Post     *string
-	Put      *string
-	Patch    *string
-	Delete   *string
-	Name     *string
-	Headers  Headers
-	Body     *Json
-	Response *Response
+	Get     *string
+	Post    *string
+	Put     *string
+	Patch   *string
+	Delete  *string
+	Head    *string
+	Options *string
+	Trace   *string
+	// CustomMethod is the method of a request with a method not listed above, e.g. a WebDAV method. It
+	// requires the URL
+	CustomMethod *string `yaml:"method,omitempty"`
+	URL          *string `yaml:"url,omitempty"`
+	Name         *string
+	Headers      Headers
+	// Query are the query parameters added to the URL, a list value adds the parameter once per item
+	Query map[string]any `yaml:"query,omitempty"`
+	Body  *Json
+	// Form is sent as an application/x-www-form-urlencoded body
+	Form map[string]any `yaml:"form,omitempty"`
+	// Multipart is sent as a multipart/form-data body, its parts can have values or files
+	Multipart []Part `yaml:"multipart,omitempty"`
+	Response  *Response
+	// Flow is the name of a TestFlow to run as this step
+	Flow *string `yaml:"flow,omitempty"`
+	// Context is "shared" to run the flow with the context of the calling flow, or "isolated" to run it
+	// in its own scope. The default is "shared"
+	Context string `yaml:"context,omitempty"`
+	// Values are evaluated before the request, and they are only visible in the step
+	Values map[string]any `yaml:"values,omitempty"`
+	// PromoteValues sets the step values in the flow, so they are visible in the next steps
+	PromoteValues bool `yaml:"promoteValues,omitempty"`
+	// With are the arguments of a reference to a global step or of a flow, only visible in the
+	// referenced step or flow
+	With map[string]any `yaml:"with,omitempty"`
+	// Outputs are the values set by a referenced global step or flow that are set in the flow, by
+	// the name to use in the flow
+	Outputs map[string]string `yaml:"outputs,omitempty"`
+	// Poll repeats the request until the response matches the expected one
+	Poll *Poll `yaml:"poll,omitempty"`
+	// Retry sends the request again when it fails by a network error or a temporary failure. The values
+	// not set are taken from the flow retry
+	Retry Retry `yaml:"retry,omitempty"`
+	// Timeout limits the time of each request of the step. The default is the global timeout
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// ContinueOnError records the step failure, but the flow continues with the next steps
+	ContinueOnError bool `yaml:"continueOnError,omitempty"`
+	// Examples run the step once per row, with the values of the row
+	Examples *Examples `yaml:"examples,omitempty"`
 }
 
-func (s StepSpec) Method() string {
-	if s.Get != nil {
-		return "GET"
+func (s StepSpec) Validate() error {
+	if err := s.validateMethod(); err != nil {
+		return err
+	}
+	if (s.With != nil || s.Outputs != nil) && !s.IsReference() && !s.IsFlow() {
+		return fmt.Errorf("with and outputs can only be used in references to steps or flows")
 	}
-	if s.Post != nil {
-		return "POST"
+	if s.IsFlow() && (s.Method() != "" || s.Body != nil || s.Response != nil) {
+		return fmt.Errorf("a flow step cannot have a request or a response")
 	}
-	if s.Put != nil {
-		return "PUT"
+	if s.Method() != "" && s.Response == nil {
+		return fmt.Errorf("a request step requires a response")
 	}
-	if s.Patch != nil {
-		return "PATCH"
+	if countSet(s.Body != nil, s.Form != nil, s.Multipart != nil) > 1 {
+		return fmt.Errorf("only one of body, form or multipart can be used")
 	}
-	if s.Delete != nil {
-		return "DELETE"
+	for _, part := range s.Multipart {
+		if err := part.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := s.validateExpressions(); err != nil {
+		return err
 	}
+	if s.Context != "" && (!s.IsFlow() || (s.Context != ContextShared && s.Context != ContextIsolated)) {
+		return fmt.Errorf("invalid context '%s', expected '%s' or '%s' in a flow step", s.Context, ContextShared, ContextIsolated)
+	}
+	if s.IsFlow() && s.Outputs != nil && s.Context != ContextIsolated {
+		return fmt.Errorf("outputs of a flow step require the '%s' context", ContextIsolated)
+	}
+	if err := s.Examples.Validate(); err != nil {
+		return err
+	}
+	if err := s.Poll.Validate(); err != nil {
+		return err
+	}
+	if err := s.Retry.Validate(); err != nil {
+		return err
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	if s.Response != nil {
+		return s.Response.Validate()
+	}
+	return nil
+}
 
-	return ""
+// validateMethod checks the step has one method, or it runs a flow or references a global step
+func (s StepSpec) validateMethod() error {
+	var methods []string
+	for _, request := range s.requests() {
+		if request.url != nil {
+			methods = append(methods, request.method)
+		}
+	}
+	if s.CustomMethod != nil {
+		methods = append(methods, *s.CustomMethod)
+	}
+
+	switch {
+	case len(methods) > 1:
+		return fmt.Errorf("only one method can be used, found %s", strings.Join(methods, ", "))
+	case s.CustomMethod != nil && s.URL == nil:
+		return fmt.Errorf("method '%s' requires a url", *s.CustomMethod)
+	case s.CustomMethod == nil && s.URL != nil:
+		return fmt.Errorf("url '%s' requires a method", *s.URL)
+	case s.CustomMethod != nil && !validMethod.MatchString(*s.CustomMethod):
+		return fmt.Errorf("invalid method '%s'", *s.CustomMethod)
+	case len(methods) == 0 && s.Flow == nil && s.Name == nil:
+		return fmt.Errorf("a step requires a method, a flow, or the name of a global step")
+	}
+	return nil
 }
 
-func (s StepSpec) Url() string {
-	if s.Get != nil {
-		return *s.Get
+// validateExpressions checks the expressions of the values evaluated when the step runs, so invalid ones are
+// reported when the spec is read
+func (s StepSpec) validateExpressions() error {
+	headerNames := make([]string, 0, len(s.Headers))
+	for name := range s.Headers {
+		headerNames = append(headerNames, name)
 	}
-	if s.Post != nil {
-		return *s.Post
+
+	fields := []struct {
+		name  string
+		value any
+	}{
+		{"url", s.Url()},
+		{"headers", headerNames},
+		{"headers", map[string]string(s.Headers)},
+		{"query", s.Query},
+		{"body", s.Body},
+		{"form", s.Form},
+		{"values", s.Values},
+		{"with", s.With},
 	}
-	if s.Put != nil {
-		return *s.Put
+
+	for _, field := range fields {
+		if err := jsonx.Validate(field.value); err != nil {
+			return fmt.Errorf("invalid %s: %w", field.name, err)
+		}
 	}
-	if s.Patch != nil {
-		return *s.Patch
+	return nil
+}
+
+// validMethod are the methods with letters, digits and dashes, e.g. VERSION-CONTROL
+var validMethod = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+type request struct {
+	method string
+	url    *string
+}
+
+// requests returns the methods with their own field in the step, and their URLs
+func (s StepSpec) requests() []request {
+	return []request{
+		{"GET", s.Get},
+		{"POST", s.Post},
+		{"PUT", s.Put},
+		{"PATCH", s.Patch},
+		{"DELETE", s.Delete},
+		{"HEAD", s.Head},
+		{"OPTIONS", s.Options},
+		{"TRACE", s.Trace},
 	}
-	if s.Delete != nil {
-		return *s.Delete
+}
+
+func countSet(values ...bool) int {
+	count := 0
+	for _, set := range values {
+		if set {
+			count++
+		}
 	}
+	return count
+}
 
-	panic("unknown method")
+// Method returns the method of the request, or "" if the step has no request
+func (s StepSpec) Method() string {
+	if s.CustomMethod != nil {
+		return strings.ToUpper(*s.CustomMethod)
+	}
+	for _, request := range s.requests() {
+		if request.url != nil {
+			return request.method
+		}
+	}
+	return ""
+}
+
+// Url returns the URL of the request, or "" if the step has no request
+func (s StepSpec) Url() string {
+	if s.URL != nil {
+		return *s.URL
+	}
+	for _, request := range s.requests() {
+		if request.url != nil {
+			return *request.url
+		}
+	}
+	return ""
 }
 
 func (s StepSpec) NameOrUrl() string {
 	if s.Name != nil {
 		return *s.Name
 	}
+	if s.Flow != nil {
+		return *s.Flow
+	}
 	return s.Url()
 }
 
 // IsReference returns true if this Step is not defined here, but references a
 // global defined step
 func (s StepSpec) IsReference() bool {
-	return s.Method() == "" && s.Body == nil && s.Response == nil
+	return s.Method() == "" && s.Body == nil && s.Form == nil && s.Multipart == nil && s.Response == nil && s.Flow == nil
+}
+
+// IsFlow returns true if this Step runs a TestFlow
+func (s StepSpec) IsFlow() bool {
+	return s.Flow != nil
+}
+
+// IsIsolated returns true if this Step runs in its own scope, so the values it sets are not visible in the
+// flow, except its outputs. References to global steps with arguments or outputs, and flows with isolated
+// context run in their own scope
+func (s StepSpec) IsIsolated() bool {
+	if s.IsFlow() {
+		return s.Context == ContextIsolated
+	}
+	return s.IsReference() && (s.With != nil || s.Outputs != nil)
 }
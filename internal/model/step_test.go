@@ -0,0 +1,113 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestStepSpec_methods(t *testing.T) {
+	response := "\nresponse: {statusCode: 200}"
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantMethod string
+		wantUrl    string
+		wantErr    string
+	}{
+		{name: "get", spec: "get: /credits" + response, wantMethod: "GET", wantUrl: "/credits"},
+		{name: "head", spec: "head: /credits" + response, wantMethod: "HEAD", wantUrl: "/credits"},
+		{name: "options", spec: "options: /credits" + response, wantMethod: "OPTIONS", wantUrl: "/credits"},
+		{name: "trace", spec: "trace: /credits" + response, wantMethod: "TRACE", wantUrl: "/credits"},
+		{name: "custom method", spec: "method: propfind\nurl: /files" + response, wantMethod: "PROPFIND", wantUrl: "/files"},
+		{name: "without response", spec: "head: /credits\nretry: {attempts: 1}", wantErr: "a request step requires a response"},
+		{name: "reference", spec: "name: create-credit"},
+		{name: "two methods", spec: "get: /a\npost: /b", wantErr: "only one method can be used, found GET, POST"},
+		{name: "method and custom method", spec: "get: /a\nmethod: LOCK\nurl: /b", wantErr: "only one method can be used, found GET, LOCK"},
+		{name: "custom method without url", spec: "method: LOCK", wantErr: "method 'LOCK' requires a url"},
+		{name: "url without method", spec: "url: /a", wantErr: "url '/a' requires a method"},
+		{name: "invalid method", spec: "method: GET /a\nurl: /a", wantErr: "invalid method 'GET /a'"},
+		{name: "no request", spec: "headers: {Accept: text/plain}", wantErr: "a step requires a method, a flow, or the name of a global step"},
+		{name: "flow with outputs", spec: "flow: login\ncontext: isolated\noutputs: {token: token}"},
+		{name: "shared flow with outputs", spec: "flow: login\noutputs: {token: token}", wantErr: "outputs of a flow step require the 'isolated' context"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var step StepSpec
+			assert.NoError(t, yaml.UnmarshalStrict([]byte(tt.spec), &step))
+
+			err := step.Validate()
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMethod, step.Method())
+			assert.Equal(t, tt.wantUrl, step.Url())
+		})
+	}
+}
+
+func TestStepSpec_validates_the_expected_body(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "directives", body: "{id: $(id), $mode: contains, $ignore: [metadata]}"},
+		{name: "invalid mode", body: "{$mode: partial}", wantErr: "invalid body: invalid $mode directive: invalid match mode 'partial', expected 'exact' or 'contains'"},
+		{name: "invalid ignore", body: "{$ignore: {a: 1}}", wantErr: "invalid body: invalid $ignore directive: expected a key or a list of keys"},
+		{name: "invalid nested expression", body: "{items: [{id: '$(id:unknown)'}]}", wantErr: "invalid body: items: 0: id: invalid expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var step StepSpec
+			assert.NoError(t, yaml.UnmarshalStrict([]byte("get: /credits\nresponse:\n  statusCode: 200\n  body: "+tt.body), &step))
+
+			err := step.Validate()
+
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStepSpec_validates_the_expressions_of_the_request(t *testing.T) {
+	response := "\nresponse: {statusCode: 200}"
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{name: "valid expressions", spec: "get: /credits/${id}\nheaders: {X-Id: '${id}'}\nquery: {id: '${id}'}\nvalues: {name: '${name:random.name}'}" + response},
+		{name: "url", spec: "get: /credits/$(id:unknown)" + response, wantErr: "invalid url: invalid expression"},
+		{name: "header name", spec: "get: /credits\nheaders: {'$(id:unknown)': a}" + response, wantErr: "invalid headers: 0: invalid expression"},
+		{name: "header value", spec: "get: /credits\nheaders: {X-Id: '$(id:unknown)'}" + response, wantErr: "invalid headers: X-Id: invalid expression"},
+		{name: "query", spec: "get: /credits\nquery: {id: ['$(id:unknown)']}" + response, wantErr: "invalid query: id: 0: invalid expression"},
+		{name: "body", spec: "post: /credits\nbody: {id: '$(id:unknown)'}" + response, wantErr: "invalid body: id: invalid expression"},
+		{name: "form", spec: "post: /credits\nform: {id: '$(id:unknown)'}" + response, wantErr: "invalid form: id: invalid expression"},
+		{name: "multipart value", spec: "post: /credits\nmultipart: [{name: id, value: '$(id:unknown)'}]" + response, wantErr: "part 'id': invalid value: invalid expression"},
+		{name: "values", spec: "get: /credits\nvalues: {id: '$(id:unknown)'}" + response, wantErr: "invalid values: id: invalid expression"},
+		{name: "with", spec: "name: get-credit\nwith: {id: '$(id:unknown)'}", wantErr: "invalid with: id: invalid expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var step StepSpec
+			assert.NoError(t, yaml.UnmarshalStrict([]byte(tt.spec), &step))
+
+			err := step.Validate()
+
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -1,11 +1,38 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type TestFlowSpec struct {
 	Environment map[string]string `yaml:"fromEnvironment,omitempty"`
 	Values      map[string]any    `yaml:"values,omitempty"`
-	Steps       []StepSpec        `yaml:"steps,omitempty"`
+	// Setup are the steps run before the steps of the flow. If they fail, the flow steps are skipped
+	Setup []StepSpec `yaml:"setup,omitempty"`
+	Steps []StepSpec `yaml:"steps,omitempty"`
+	// Teardown are the steps run after the flow, even if the flow fails or is interrupted
+	Teardown []StepSpec `yaml:"teardown,omitempty"`
+	// Comparison is the default comparison for the responses of the steps
+	Comparison Comparison `yaml:"comparison,omitempty"`
+	// Retry is the default retry for the requests of the steps
+	Retry Retry `yaml:"retry,omitempty"`
+	// Timeout limits the time to run the setup and the steps of the flow. The teardown steps are run anyway
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Auth authenticates the requests of the steps
+	Auth *Auth `yaml:"auth,omitempty"`
+	// TLS configures the TLS connections of the requests, on top of the global TLS configuration
+	TLS *TLS `yaml:"tls,omitempty"`
+	// Examples run the flow once per row, with the values of the row
+	Examples *Examples `yaml:"examples,omitempty"`
+}
+
+// AllSteps returns the setup steps, the flow steps and the teardown steps
+func (s TestFlowSpec) AllSteps() []StepSpec {
+	var steps []StepSpec
+	steps = append(steps, s.Setup...)
+	steps = append(steps, s.Steps...)
+	return append(steps, s.Teardown...)
 }
 
 type TestFlow struct {
@@ -14,6 +41,7 @@ type TestFlow struct {
 	Metadata   Metadata     `yaml:"metadata"`
 	Spec       TestFlowSpec `yaml:"spec"`
 	parent     *TestFlowCollection
+	sourceFile string
 }
 
 func (t *TestFlow) Validate() error {
@@ -25,6 +53,36 @@ func (t *TestFlow) Validate() error {
 		return err
 	}
 
+	if err := t.Spec.Comparison.Validate(); err != nil {
+		return err
+	}
+
+	if err := t.Spec.Retry.Validate(); err != nil {
+		return err
+	}
+
+	if t.Spec.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+
+	if err := t.Spec.Auth.Validate(); err != nil {
+		return err
+	}
+
+	if err := t.Spec.TLS.Validate(); err != nil {
+		return err
+	}
+
+	if err := t.Spec.Examples.Validate(); err != nil {
+		return err
+	}
+
+	for _, step := range t.Spec.AllSteps() {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("step '%s': %w", step.NameOrUrl(), err)
+		}
+	}
+
 	return nil
 }
 
@@ -36,6 +94,20 @@ func (t *TestFlow) GetGlobalStepSpec(name string) (*StepSpec, bool) {
 	return t.parent.GetGlobalStepSpec(name)
 }
 
+// GetTestFlow returns the flow with the given name, to be run as a step of this flow
+func (t *TestFlow) GetTestFlow(name string) (*TestFlow, bool) {
+	return t.parent.GetTestFlow(name)
+}
+
 func (t *TestFlow) SetParent(parent *TestFlowCollection) {
 	t.parent = parent
 }
+
+// SourceFile returns the file the flow was read from
+func (t *TestFlow) SourceFile() string {
+	return t.sourceFile
+}
+
+func (t *TestFlow) SetSourceFile(sourceFile string) {
+	t.sourceFile = sourceFile
+}
@@ -1,5 +1,12 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/totemcaf/test-by-example.git/internal/collections/maps"
+)
+
 type TestFlowCollection struct {
 	Flows       map[string]*TestFlow
 	GlobalSteps map[string]*Step
@@ -33,3 +40,65 @@ func (c *TestFlowCollection) GetGlobalStepSpec(name string) (*StepSpec, bool) {
 	}
 	return nil, false
 }
+
+// CheckFlowSteps verifies the flows run by the steps of other flows exist, and that no flow runs itself,
+// directly or through other flows
+func (c *TestFlowCollection) CheckFlowSteps() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(c.Flows))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("flows cannot run themselves: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, called := range c.calledFlows(c.Flows[name]) {
+			if !c.HasFlow(called) {
+				return fmt.Errorf("flow '%s' run in flow '%s' not found", called, name)
+			}
+			if err := visit(called, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, name := range maps.SortedKeys(c.Flows) {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// calledFlows returns the names of the flows run by the steps of the flow, or by the global steps they reference
+func (c *TestFlowCollection) calledFlows(flow *TestFlow) []string {
+	var names []string
+
+	for _, step := range flow.Spec.AllSteps() {
+		if step.IsReference() {
+			if globalStep, found := c.GlobalSteps[step.NameOrUrl()]; found {
+				step = globalStep.Spec
+			}
+		}
+		if step.IsFlow() {
+			names = append(names, *step.Flow)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFlowCalling(name string, flows ...string) *TestFlow {
+	flow := &TestFlow{Metadata: Metadata{Name: name}}
+	for _, called := range flows {
+		called := called
+		flow.Spec.Steps = append(flow.Spec.Steps, StepSpec{Flow: &called})
+	}
+	return flow
+}
+
+func TestTestFlowCollection_CheckFlowSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		flows   []*TestFlow
+		wantErr string
+	}{
+		{
+			name:  "flows without cycles",
+			flows: []*TestFlow{newFlowCalling("a", "b", "c"), newFlowCalling("b", "c"), newFlowCalling("c")},
+		},
+		{
+			name:    "flow that runs itself",
+			flows:   []*TestFlow{newFlowCalling("a", "a")},
+			wantErr: "flows cannot run themselves: a -> a",
+		},
+		{
+			name:    "flows that run each other",
+			flows:   []*TestFlow{newFlowCalling("a", "b"), newFlowCalling("b", "c"), newFlowCalling("c", "a")},
+			wantErr: "flows cannot run themselves: a -> b -> c -> a",
+		},
+		{
+			name:    "missing flow",
+			flows:   []*TestFlow{newFlowCalling("a", "z")},
+			wantErr: "flow 'z' run in flow 'a' not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := &TestFlowCollection{Flows: map[string]*TestFlow{}}
+			for _, flow := range tt.flows {
+				collection.Flows[flow.Metadata.Name] = flow
+			}
+
+			err := collection.CheckFlowSteps()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestFlowCollection_CheckFlowSteps_through_global_steps(t *testing.T) {
+	login := "login"
+	collection := &TestFlowCollection{
+		Flows: map[string]*TestFlow{"login": newFlowCalling("login")},
+		GlobalSteps: map[string]*Step{
+			"do-login": {Spec: StepSpec{Flow: &login}},
+		},
+	}
+	stepName := "do-login"
+	collection.Flows["login"].Spec.Steps = []StepSpec{{Name: &stepName}}
+
+	assert.EqualError(t, collection.CheckFlowSteps(), "flows cannot run themselves: login -> login")
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// TLS configures the TLS connections of the requests, e.g. to trust a private CA or to authenticate
+// with a client certificate
+type TLS struct {
+	// CACerts are PEM files with the certificates of the CAs trusted, besides the CAs of the system
+	CACerts []string `yaml:"caCerts,omitempty"`
+	// ClientCerts are the certificates sent when the server requests a client certificate
+	ClientCerts []ClientCert `yaml:"clientCerts,omitempty"`
+	// ServerName is the name used to verify the certificate of the server, by default the host of the request
+	ServerName string `yaml:"serverName,omitempty"`
+	// InsecureSkipVerify accepts any certificate of the server. Use it only in tests
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify,omitempty"`
+	// dir is the folder of the spec file, relative file paths are resolved from it
+	dir string
+}
+
+// ClientCert is a pair of PEM files with a certificate and its private key
+type ClientCert struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+func (t *TLS) Validate() error {
+	if t == nil {
+		return nil
+	}
+
+	for _, file := range t.CACerts {
+		if file == "" {
+			return fmt.Errorf("tls caCerts cannot have empty paths")
+		}
+	}
+
+	for _, cert := range t.ClientCerts {
+		if cert.Cert == "" || cert.Key == "" {
+			return fmt.Errorf("tls client cert requires a cert and a key")
+		}
+	}
+
+	return nil
+}
+
+// SetDir sets the folder of the spec file the TLS was read from
+func (t *TLS) SetDir(dir string) {
+	if t != nil {
+		t.dir = dir
+	}
+}
+
+// Path returns the path of the given file with the environment variables expanded, relative to the folder
+// of the spec file if it is not absolute
+func (t *TLS) Path(file string) string {
+	file = os.ExpandEnv(file)
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(t.dir, file)
+}
@@ -0,0 +1,97 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"gopkg.in/yaml.v2"
+)
+
+// readExamples reads the rows of the examples defined in a file. The path is relative to the
+// spec file. Examples listed in the spec are left as they are.
+func readExamples(examples *model.Examples, specFile string) error {
+	if examples == nil || examples.File == "" {
+		return nil
+	}
+
+	fileName := examples.File
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(filepath.Dir(specFile), fileName)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		examples.Rows, err = parseCsvExamples(data)
+	case ".json":
+		examples.Rows, err = parseJsonExamples(data)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &examples.Rows)
+	default:
+		err = fmt.Errorf("unknown examples file type, expected .csv, .json or .yaml")
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid examples file %s: %w", examples.File, err)
+	}
+
+	return nil
+}
+
+// parseCsvExamples reads a row for each CSV record. The first record has the column names, and the
+// values are read as YAML scalars, so numbers and booleans keep their type
+func parseCsvExamples(data []byte) ([]map[string]any, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]any, 0, len(records)-1)
+
+	for _, record := range records[1:] {
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			row[name] = parseCsvValue(record[i])
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseCsvValue(text string) any {
+	var value any
+	if text == "" || yaml.Unmarshal([]byte(text), &value) != nil {
+		return text
+	}
+
+	switch value.(type) {
+	case int, float64, bool:
+		return value
+	}
+	return text
+}
+
+func parseJsonExamples(data []byte) ([]map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var rows []map[string]any
+	err := decoder.Decode(&rows)
+
+	return rows, err
+}
@@ -0,0 +1,70 @@
+package parsers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+func Test_read_examples_from_files(t *testing.T) {
+	dir := t.TempDir()
+	specFile := filepath.Join(dir, "flow.yaml")
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []map[string]any
+	}{
+		{
+			name:    "csv values keep their type",
+			file:    "amounts.csv",
+			content: "amount,valid,name\n100,true,James\n0.5,false,\n",
+			want: []map[string]any{
+				{"amount": 100, "valid": true, "name": "James"},
+				{"amount": 0.5, "valid": false, "name": ""},
+			},
+		},
+		{
+			name:    "json",
+			file:    "amounts.json",
+			content: `[{"amount": 100, "name": "James"}]`,
+			want:    []map[string]any{{"amount": json.Number("100"), "name": "James"}},
+		},
+		{
+			name:    "yaml",
+			file:    "data/amounts.yaml",
+			content: "- amount: 100\n  name: James\n",
+			want:    []map[string]any{{"amount": 100, "name": "James"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN an examples file next to the spec
+			path := filepath.Join(dir, tt.file)
+			assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+			examples := &model.Examples{File: tt.file}
+
+			// WHEN the examples are read
+			err := readExamples(examples, specFile)
+
+			// THEN the rows are read with the path relative to the spec
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, examples.Rows)
+		})
+	}
+}
+
+func Test_read_examples_with_unknown_file_type(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "amounts.txt"), []byte("100"), 0o644))
+
+	err := readExamples(&model.Examples{File: "amounts.txt"}, filepath.Join(dir, "flow.yaml"))
+
+	assert.Error(t, err)
+}
@@ -1,7 +1,10 @@
 package parsers
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/totemcaf/test-by-example.git/internal/model"
 	"go.uber.org/zap"
@@ -11,6 +14,7 @@ import (
 type DocumentType interface {
 	*model.TestFlow | *model.Step
 	Validate() error
+	SetSourceFile(sourceFile string)
 }
 
 func ReadSpec[Spec DocumentType](fileName string) (Spec, error) {
@@ -24,39 +28,136 @@ func ReadSpec[Spec DocumentType](fileName string) (Spec, error) {
 
 	err = yaml.UnmarshalStrict(bytes, &spec)
 
-	if err == nil {
-		err = spec.Validate()
+	if err != nil {
+		return spec, err
+	}
+
+	spec.SetSourceFile(fileName)
+
+	if err = readDocumentExamples(spec, fileName); err != nil {
+		return spec, err
+	}
+
+	setFilesDir(spec, filepath.Dir(fileName))
+
+	return spec, spec.Validate()
+}
+
+// readDocumentExamples reads the examples files of the document and its steps
+func readDocumentExamples(document any, fileName string) error {
+	switch doc := document.(type) {
+	case *model.TestFlow:
+		if err := readExamples(doc.Spec.Examples, fileName); err != nil {
+			return err
+		}
+		for _, step := range doc.Spec.AllSteps() {
+			if err := readExamples(step.Examples, fileName); err != nil {
+				return fmt.Errorf("step '%s': %w", step.NameOrUrl(), err)
+			}
+		}
+	case *model.Step:
+		return readExamples(doc.Spec.Examples, fileName)
+	}
+	return nil
+}
+
+// setFilesDir sets the folder of the spec file in the TLS of the flow and in the multipart parts of the steps,
+// so their files are read relative to it
+func setFilesDir(document any, dir string) {
+	var steps []model.StepSpec
+
+	switch doc := document.(type) {
+	case *model.TestFlow:
+		doc.Spec.TLS.SetDir(dir)
+		steps = doc.Spec.AllSteps()
+	case *model.Step:
+		steps = []model.StepSpec{doc.Spec}
+	}
+
+	for _, step := range steps {
+		for i := range step.Multipart {
+			step.Multipart[i].SetDir(dir)
+		}
+	}
+}
+
+// readKind returns the kind of the document in the file, or an empty kind when the document has no kind,
+// like the examples files, which are lists of rows
+func readKind(fileName string) (string, error) {
+	bytes, err := os.ReadFile(fileName)
+
+	if err != nil {
+		return "", err
+	}
+
+	var document interface{}
+
+	if err = yaml.Unmarshal(bytes, &document); err != nil {
+		return "", err
 	}
 
-	return spec, err
+	fields, _ := document.(map[interface{}]interface{})
+	kind, _ := fields["kind"].(string)
+
+	return kind, nil
 }
 
+// ReadTestFlowCollectionFrom reads all the files. Files without kind, like the examples files, are skipped.
+// All the files are read, even if some of them cannot be read or are not valid, so all of them are logged, and
+// the returned error lists the failed files. The returned collection only has the valid files, callers should not
+// run it when there is an error.
 func ReadTestFlowCollectionFrom(logger *zap.SugaredLogger, files []string) (model.TestFlowCollection, error) {
 	collection := model.TestFlowCollection{
 		Flows:       make(map[string]*model.TestFlow, 0),
 		GlobalSteps: make(map[string]*model.Step, 0),
 	}
 
+	var failed []string
+
 	for _, file := range files {
-		testFlow, err := ReadSpec[*model.TestFlow](file)
+		if err := readInto(logger, &collection, file); err != nil {
+			logger.Errorf("Failed to read %s: %s", file, err.Error())
+			failed = append(failed, file)
+		}
+	}
 
-		if err == nil {
-			name := testFlow.Metadata.Name
-			logger.Infof("Read '%s' from %s", testFlow.FullName(), file)
-			collection.Flows[name] = testFlow
-			continue
+	if len(failed) > 0 {
+		return collection, fmt.Errorf("failed to read: %s", strings.Join(failed, ", "))
+	}
+
+	return collection, collection.CheckFlowSteps()
+}
+
+func readInto(logger *zap.SugaredLogger, collection *model.TestFlowCollection, file string) error {
+	kind, err := readKind(file)
+
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "":
+		logger.Debugf("Skipping %s, it is not a %s nor a %s", file, model.TestFlowKind, model.TestStepKind)
+
+	case model.TestFlowKind:
+		testFlow, err := ReadSpec[*model.TestFlow](file)
+		if err != nil {
+			return err
 		}
+		logger.Infof("Read '%s' from %s", testFlow.FullName(), file)
+		collection.Flows[testFlow.Metadata.Name] = testFlow
 
+	case model.TestStepKind:
 		step, err := ReadSpec[*model.Step](file)
-		if err == nil {
-			name := step.Metadata.Name
-			logger.Infof("Read '%s' from %s", step.FullName(), file)
-			collection.GlobalSteps[name] = step
-			continue
+		if err != nil {
+			return err
 		}
+		logger.Infof("Read '%s' from %s", step.FullName(), file)
+		collection.GlobalSteps[step.Metadata.Name] = step
 
-		logger.Errorf("Failed to read %s, skipping it. %s", file, err.Error())
+	default:
+		return fmt.Errorf("unknown kind '%s'", kind)
 	}
 
-	return collection, nil
+	return nil
 }
@@ -0,0 +1,58 @@
+package parsers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_read_collection_skips_files_without_kind(t *testing.T) {
+	// GIVEN a folder with a flow that reads its examples from a YAML file
+	dir := t.TempDir()
+	flow := "apiVersion: test/v1-alpha\nkind: TestFlow\nmetadata:\n  name: rows\nspec:\n  examples: examples/rows.yaml\n" +
+		"  steps:\n    - get: http://localhost/${id}\n      response:\n        statusCode: 200\n"
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "examples"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "flow.yaml"), []byte(flow), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "examples", "rows.yaml"), []byte("- id: 1\n- id: 2\n"), 0o644))
+
+	tests := []struct {
+		name    string
+		extra   string
+		wantErr string
+	}{
+		{
+			name: "examples file",
+		},
+		{
+			name:    "unknown kind",
+			extra:   "kind: TestFlw\n",
+			wantErr: "failed to read: " + filepath.Join(dir, "other.yaml"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := []string{filepath.Join(dir, "flow.yaml"), filepath.Join(dir, "examples", "rows.yaml")}
+			if tt.extra != "" {
+				files = append(files, filepath.Join(dir, "other.yaml"))
+				assert.NoError(t, os.WriteFile(files[2], []byte(tt.extra), 0o644))
+			}
+
+			// WHEN all the files of the folder are read
+			collection, err := ReadTestFlowCollectionFrom(zap.NewNop().Sugar(), files)
+
+			// THEN the examples file is skipped, and only the documents with an unknown kind fail
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if assert.Contains(t, collection.Flows, "rows") {
+				assert.Len(t, collection.Flows["rows"].Spec.Examples.Rows, 2)
+			}
+			assert.Empty(t, collection.GlobalSteps)
+		})
+	}
+}
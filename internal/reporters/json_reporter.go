@@ -0,0 +1,27 @@
+package reporters
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/totemcaf/test-by-example.git/internal/results"
+)
+
+type jsonReporter struct {
+	path string
+}
+
+// NewJsonReporter returns a reporter that writes the whole report as a JSON document
+func NewJsonReporter(path string) Reporter {
+	return &jsonReporter{path: path}
+}
+
+func (j *jsonReporter) Write(report *results.Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(j.path, data, 0644)
+}
@@ -0,0 +1,146 @@
+package reporters
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/internal/results"
+)
+
+type junitReporter struct {
+	path string
+}
+
+// NewJUnitReporter returns a reporter that writes a JUnit XML document, with a test suite for
+// each flow and a test case for each step. The steps of a flow run as a step are test cases named
+// after the steps that contain them, e.g. "login / get-token"
+func NewJUnitReporter(path string) Reporter {
+	return &junitReporter{path: path}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+func (j *junitReporter) Write(report *results.Report) error {
+	suites := junitTestSuites{Time: seconds(report.Duration)}
+
+	for _, flow := range report.Flows {
+		suite := toJUnitTestSuite(flow)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(j.path, append([]byte(xml.Header), data...), 0644)
+}
+
+func toJUnitTestSuite(flow *results.FlowResult) junitTestSuite {
+	suite := junitTestSuite{
+		Name:      flowName(flow),
+		Failures:  flow.Count(results.Failed),
+		Skipped:   flow.Count(results.Skipped),
+		Time:      seconds(flow.Duration),
+		Timestamp: flow.Start.Format("2006-01-02T15:04:05"),
+	}
+
+	suite.Cases = toJUnitTestCases(flow.Name, "", flow.Steps)
+
+	// A flow can fail before or after its steps, e.g. when its values are not valid. It is reported as a failed
+	// test case named after the flow, so the suite is not seen as passed
+	if flow.Status == results.Failed && suite.Failures == 0 {
+		message, _, _ := strings.Cut(flow.Error, "\n")
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      flow.Name,
+			ClassName: flow.Name,
+			Time:      seconds(flow.Duration),
+			Failure:   &junitFailure{Message: message, Text: flow.Error},
+		})
+		suite.Failures++
+	}
+
+	suite.Tests = len(suite.Cases)
+
+	return suite
+}
+
+// toJUnitTestCases returns a test case for each step, followed by the test cases of its nested steps
+func toJUnitTestCases(className string, prefix string, steps []*results.StepResult) []junitTestCase {
+	var testCases []junitTestCase
+
+	for _, step := range steps {
+		testCase := junitTestCase{
+			Name:      prefix + step.Name,
+			ClassName: className,
+			Time:      seconds(step.Duration),
+		}
+
+		switch step.Status {
+		case results.Failed:
+			testCase.Failure = toJUnitFailure(step)
+		case results.Skipped:
+			testCase.Skipped = &struct{}{}
+		}
+
+		testCases = append(testCases, testCase)
+		testCases = append(testCases, toJUnitTestCases(className, testCase.Name+" / ", step.Steps)...)
+	}
+
+	return testCases
+}
+
+// toJUnitFailure uses the first line of the error as message, and the differences, if any, as the failure text
+func toJUnitFailure(step *results.StepResult) *junitFailure {
+	message, _, _ := strings.Cut(step.Error, "\n")
+	text := step.Error
+
+	if len(step.Differences) > 0 {
+		message = fmt.Sprintf("%d differences found", len(step.Differences))
+		text = step.Differences.String()
+	}
+
+	return &junitFailure{Message: message, Text: text}
+}
+
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,124 @@
+package reporters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/results"
+	"github.com/totemcaf/test-by-example.git/pkg/jsonx"
+)
+
+func Test_junit_reporter_writes_flows_as_suites_and_steps_as_cases(t *testing.T) {
+	// GIVEN a report with a flow step, and a passed, a failed and a skipped step
+	start := time.Date(2022, 8, 1, 10, 20, 30, 0, time.UTC)
+	report := &results.Report{
+		Start:    start,
+		Duration: 1500 * time.Millisecond,
+		Flows: []*results.FlowResult{{
+			Name:       "credit-flow",
+			Repetition: 1,
+			Status:     results.Failed,
+			Start:      start,
+			Duration:   1200 * time.Millisecond,
+			Steps: []*results.StepResult{
+				{
+					Name:     "login",
+					Status:   results.Passed,
+					Duration: 100 * time.Millisecond,
+					Steps:    []*results.StepResult{{Name: "get-token", Status: results.Passed, Duration: 100 * time.Millisecond}},
+				},
+				{Name: "create-client", Status: results.Passed, Duration: 200 * time.Millisecond},
+				{
+					Name:     "start-bnpl",
+					Status:   results.Failed,
+					Duration: time.Second,
+					Error:    "amount: different",
+					Differences: jsonx.Differences{{
+						Path:     []string{"amount"},
+						Expected: jsonx.NewParser().Parse("2200.40"),
+						Actual:   jsonx.NewParser().Parse("2200.41"),
+						Message:  "different",
+					}},
+				},
+				{Name: "approve", Status: results.Skipped},
+			},
+		}},
+	}
+	path := filepath.Join(t.TempDir(), "report.xml")
+
+	// WHEN the report is written
+	reporter, err := New("junit=" + path)
+	assert.NoError(t, err)
+	assert.NoError(t, reporter.Write(report))
+
+	// THEN the file has the suites and cases, with the steps of the flow step named after it
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="5" failures="1" skipped="1" time="1.500">
+  <testsuite name="credit-flow" tests="5" failures="1" skipped="1" time="1.200" timestamp="2022-08-01T10:20:30">
+    <testcase name="login" classname="credit-flow" time="0.100"></testcase>
+    <testcase name="login / get-token" classname="credit-flow" time="0.100"></testcase>
+    <testcase name="create-client" classname="credit-flow" time="0.200"></testcase>
+    <testcase name="start-bnpl" classname="credit-flow" time="1.000">
+      <failure message="1 differences found"><![CDATA[amount: different.
+  Expected: 2200.40
+  Actual: 2200.41
+]]></failure>
+    </testcase>
+    <testcase name="approve" classname="credit-flow" time="0.000">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>`, string(data))
+}
+
+func Test_new_reporter_with_invalid_definitions(t *testing.T) {
+	_, err := New("junit")
+	assert.Error(t, err)
+
+	_, err = New("html=report.html")
+	assert.Error(t, err)
+}
+
+func Test_junit_reporter_writes_a_failed_case_for_flows_failed_without_failed_steps(t *testing.T) {
+	// GIVEN a report with a flow that failed before running its steps
+	start := time.Date(2022, 8, 1, 10, 20, 30, 0, time.UTC)
+	report := &results.Report{
+		Start:    start,
+		Duration: 100 * time.Millisecond,
+		Flows: []*results.FlowResult{{
+			Name:     "credit-flow",
+			Status:   results.Failed,
+			Start:    start,
+			Duration: 100 * time.Millisecond,
+			Steps:    []*results.StepResult{{Name: "create-client", Status: results.Skipped}},
+			Error:    "invalid values\nunknown generator",
+		}},
+	}
+	path := filepath.Join(t.TempDir(), "report.xml")
+
+	// WHEN the report is written
+	reporter, err := New("junit=" + path)
+	assert.NoError(t, err)
+	assert.NoError(t, reporter.Write(report))
+
+	// THEN the suite has a failed case named after the flow, with the error of the flow
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="2" failures="1" skipped="1" time="0.100">
+  <testsuite name="credit-flow" tests="2" failures="1" skipped="1" time="0.100" timestamp="2022-08-01T10:20:30">
+    <testcase name="create-client" classname="credit-flow" time="0.000">
+      <skipped></skipped>
+    </testcase>
+    <testcase name="credit-flow" classname="credit-flow" time="0.100">
+      <failure message="invalid values"><![CDATA[invalid values
+unknown generator]]></failure>
+    </testcase>
+  </testsuite>
+</testsuites>`, string(data))
+}
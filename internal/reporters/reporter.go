@@ -0,0 +1,45 @@
+package reporters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/totemcaf/test-by-example.git/internal/results"
+)
+
+// Reporter writes the results of a run
+type Reporter interface {
+	Write(report *results.Report) error
+}
+
+type factory func(path string) Reporter
+
+var formats = map[string]factory{
+	"json":  NewJsonReporter,
+	"junit": NewJUnitReporter,
+}
+
+// New returns the reporter for a "format=path" definition
+func New(definition string) (Reporter, error) {
+	format, path, found := strings.Cut(definition, "=")
+
+	if !found || path == "" {
+		return nil, fmt.Errorf("invalid report '%s', expected format=path", definition)
+	}
+
+	newReporter, found := formats[format]
+
+	if !found {
+		return nil, fmt.Errorf("unknown report format '%s'", format)
+	}
+
+	return newReporter(path), nil
+}
+
+// flowName returns the flow name, with the repetition number after the first repetition
+func flowName(flow *results.FlowResult) string {
+	if flow.Repetition > 1 {
+		return fmt.Sprintf("%s (%d)", flow.Name, flow.Repetition)
+	}
+	return flow.Name
+}
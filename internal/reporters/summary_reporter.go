@@ -0,0 +1,52 @@
+package reporters
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/internal/results"
+)
+
+type summaryReporter struct {
+	writer io.Writer
+}
+
+// NewSummaryReporter returns a reporter that writes a table with the status of each flow. The steps of the
+// flows run as steps are counted with the other steps
+func NewSummaryReporter(writer io.Writer) Reporter {
+	return &summaryReporter{writer: writer}
+}
+
+func (s *summaryReporter) Write(report *results.Report) error {
+	table := tabwriter.NewWriter(s.writer, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(table, "FLOW\tSTATUS\tPASSED\tFAILED\tSKIPPED\tDURATION")
+
+	count := map[results.Status]int{}
+	for _, flow := range report.Flows {
+		count[flow.Status]++
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%s\n",
+			flowName(flow),
+			flow.Status,
+			flow.Count(results.Passed),
+			flow.Count(results.Failed),
+			flow.Count(results.Skipped),
+			flow.Duration.Round(time.Millisecond),
+		)
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	total := fmt.Sprintf("%d flows, %d passed, %d failed", len(report.Flows), count[results.Passed], count[results.Failed])
+	if count[results.Skipped] > 0 {
+		total += fmt.Sprintf(", %d skipped", count[results.Skipped])
+	}
+
+	_, err := fmt.Fprintf(s.writer, "\n%s in %s\n", total, report.Duration.Round(time.Millisecond))
+
+	return err
+}
@@ -0,0 +1,62 @@
+package reporters
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/results"
+)
+
+func Test_summary_reporter_writes_a_row_per_flow(t *testing.T) {
+	// GIVEN a report with a passed flow with a flow step, a failed repetition, and a skipped one
+	report := &results.Report{
+		Duration: 1500 * time.Millisecond,
+		Flows: []*results.FlowResult{
+			{
+				Name:       "credit-flow",
+				Repetition: 1,
+				Status:     results.Passed,
+				Duration:   300 * time.Millisecond,
+				Steps: []*results.StepResult{
+					{Name: "login", Status: results.Passed, Steps: []*results.StepResult{{Name: "get-token", Status: results.Passed}}},
+					{Name: "create-client", Status: results.Passed},
+					{Name: "approve", Status: results.Passed},
+				},
+			},
+			{
+				Name:       "credit-flow",
+				Repetition: 2,
+				Status:     results.Failed,
+				Duration:   1200 * time.Millisecond,
+				Steps: []*results.StepResult{
+					{Name: "create-client", Status: results.Failed},
+					{Name: "approve", Status: results.Skipped},
+				},
+			},
+			{
+				Name:       "credit-flow",
+				Repetition: 3,
+				Status:     results.Skipped,
+				Steps: []*results.StepResult{
+					{Name: "create-client", Status: results.Skipped},
+					{Name: "approve", Status: results.Skipped},
+				},
+			},
+		},
+	}
+	var out bytes.Buffer
+
+	// WHEN the summary is written
+	assert.NoError(t, NewSummaryReporter(&out).Write(report))
+
+	// THEN there is a row per flow, counting the steps of the flow step, and the totals
+	assert.Equal(t, `FLOW             STATUS   PASSED  FAILED  SKIPPED  DURATION
+credit-flow      passed   4       0       0        300ms
+credit-flow (2)  failed   0       1       1        1.2s
+credit-flow (3)  skipped  0       0       2        0s
+
+3 flows, 1 passed, 1 failed, 1 skipped in 1.5s
+`, out.String())
+}
@@ -0,0 +1,178 @@
+package results
+
+import (
+	"time"
+
+	"github.com/totemcaf/test-by-example.git/pkg/jsonx"
+)
+
+type Status string
+
+const (
+	Passed  Status = "passed"
+	Failed  Status = "failed"
+	Skipped Status = "skipped"
+)
+
+// Phase is the part of the flow a step belongs to
+type Phase string
+
+const (
+	Setup    Phase = "setup"
+	Main     Phase = ""
+	Teardown Phase = "teardown"
+)
+
+// Report contains the results of all the flows executed in a run
+type Report struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Flows    []*FlowResult `json:"flows"`
+}
+
+// FlowResult contains the result of a TestFlow execution
+type FlowResult struct {
+	Name       string        `json:"name"`
+	Repetition int           `json:"repetition,omitempty"`
+	Status     Status        `json:"status"`
+	Start      time.Time     `json:"start"`
+	Duration   time.Duration `json:"duration"`
+	Steps      []*StepResult `json:"steps"`
+	Error      string        `json:"error,omitempty"`
+	phase      Phase
+}
+
+// StepResult contains the result of a step execution, with the request sent and the response received
+type StepResult struct {
+	Name        string            `json:"name"`
+	Phase       Phase             `json:"phase,omitempty"`
+	Status      Status            `json:"status"`
+	Start       time.Time         `json:"start"`
+	Duration    time.Duration     `json:"duration"`
+	Request     *Request          `json:"request,omitempty"`
+	Response    *Response         `json:"response,omitempty"`
+	Differences jsonx.Differences `json:"differences,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	// Attempts are the requests sent in the step, e.g. when it is retried or polled. The last one is the result of the step
+	Attempts []*Attempt `json:"attempts,omitempty"`
+	// Steps are the results of the steps of the flow run by this step
+	Steps []*StepResult `json:"steps,omitempty"`
+}
+
+// Attempt is one of the requests sent in a step
+type Attempt struct {
+	Start      time.Time     `json:"start"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Request is the evaluated request sent in a step
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+// Response is the response received in a step
+type Response struct {
+	StatusCode int                 `json:"statusCode"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       any                 `json:"body,omitempty"`
+	Duration   time.Duration       `json:"duration"`
+}
+
+func NewReport() *Report {
+	return &Report{Start: time.Now()}
+}
+
+func (r *Report) Add(flow *FlowResult) {
+	r.Flows = append(r.Flows, flow)
+}
+
+func (r *Report) Finish() {
+	r.Duration = time.Since(r.Start)
+}
+
+// Passed returns true if all the flows passed
+func (r *Report) Passed() bool {
+	for _, flow := range r.Flows {
+		if flow.Status != Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func NewFlowResult(name string) *FlowResult {
+	return &FlowResult{Name: name, Status: Passed, Start: time.Now()}
+}
+
+// StartPhase sets the phase of the steps added from now on
+func (f *FlowResult) StartPhase(phase Phase) {
+	f.phase = phase
+}
+
+// StartStep adds a new step result, and starts measuring its duration
+func (f *FlowResult) StartStep(name string) *StepResult {
+	step := &StepResult{Name: name, Phase: f.phase, Status: Passed, Start: time.Now()}
+	f.Steps = append(f.Steps, step)
+	return step
+}
+
+// SkipStep adds a step that was not executed
+func (f *FlowResult) SkipStep(name string) {
+	f.Steps = append(f.Steps, &StepResult{Name: name, Phase: f.phase, Status: Skipped})
+}
+
+// Finish sets the flow duration, and its status from the given error
+func (f *FlowResult) Finish(err error) {
+	f.Duration = time.Since(f.Start)
+	if err != nil {
+		f.Status = Failed
+		f.Error = err.Error()
+	}
+}
+
+// Skip sets the status of a flow that was not executed
+func (f *FlowResult) Skip() {
+	f.Status = Skipped
+}
+
+// Count returns the number of steps with the given status, including the steps of the flows run as steps
+func (f *FlowResult) Count(status Status) int {
+	return countSteps(f.Steps, status)
+}
+
+func countSteps(steps []*StepResult, status Status) int {
+	count := 0
+	for _, step := range steps {
+		if step.Status == status {
+			count++
+		}
+		count += countSteps(step.Steps, status)
+	}
+	return count
+}
+
+// Finish sets the step duration, and its status from the given error
+func (s *StepResult) Finish(err error) {
+	s.Duration = time.Since(s.Start)
+	if err != nil {
+		s.Status = Failed
+		s.Error = err.Error()
+	}
+}
+
+// AddAttempt records a request sent in the step, with its response if any, and its error
+func (s *StepResult) AddAttempt(start time.Time, err error) {
+	attempt := &Attempt{Start: start, Duration: time.Since(start)}
+	if s.Response != nil {
+		attempt.StatusCode = s.Response.StatusCode
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	s.Attempts = append(s.Attempts, attempt)
+}
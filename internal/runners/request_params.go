@@ -0,0 +1,155 @@
+package runners
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/totemcaf/test-by-example.git/internal/collections/maps"
+	"github.com/totemcaf/test-by-example.git/internal/evaluators"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+// setQuery evaluates the query parameters and adds them to the request, they are encoded when the request is sent
+func (r *testRunner) setQuery(request *resty.Request, query map[string]any) error {
+	if query == nil {
+		return nil
+	}
+
+	values, err := r.evaluateParams(query)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	fmt.Fprintf(r.out, "Using Query: %s\n", values.Encode())
+	request.SetQueryParamsFromValues(values)
+	return nil
+}
+
+// setForm evaluates the form fields and sends them as an application/x-www-form-urlencoded body
+func (r *testRunner) setForm(request *resty.Request, form map[string]any) error {
+	if form == nil {
+		return nil
+	}
+
+	values, err := r.evaluateParams(form)
+	if err != nil {
+		return fmt.Errorf("form: %w", err)
+	}
+
+	fmt.Fprintf(r.out, "\nUsing Form: %s\n\n", values.Encode())
+	request.SetFormDataFromValues(values)
+	return nil
+}
+
+// setMultipart sends the parts as a multipart/form-data body. The files are read each time the request is sent
+func (r *testRunner) setMultipart(request *resty.Request, parts []model.Part) error {
+	if parts == nil {
+		return nil
+	}
+
+	eval := evaluators.NewJsonXEvaluator(r.RunningContext)
+	fields := make([]*resty.MultipartField, 0, len(parts))
+
+	for _, part := range parts {
+		field := &resty.MultipartField{Param: eval.EvaluateStr(part.Name)}
+
+		if part.File == "" {
+			values, err := paramValues(eval.Evaluate(part.Value))
+			if err != nil {
+				return fmt.Errorf("part '%s': %w", part.Name, err)
+			}
+			for _, value := range values {
+				fields = append(fields, &resty.MultipartField{Param: field.Param, Reader: bytes.NewReader([]byte(value))})
+			}
+			continue
+		}
+
+		fileName := part.Path(eval.EvaluateStr(part.File))
+		content, err := os.ReadFile(fileName)
+		if err != nil {
+			return fmt.Errorf("part '%s': %w", part.Name, err)
+		}
+
+		field.FileName = eval.EvaluateStr(part.FileName)
+		if field.FileName == "" {
+			field.FileName = filepath.Base(fileName)
+		}
+		field.ContentType = eval.EvaluateStr(part.ContentType)
+		if field.ContentType == "" {
+			field.ContentType = http.DetectContentType(content)
+		}
+		field.Reader = bytes.NewReader(content)
+
+		fmt.Fprintf(r.out, "Using File: %s=%s (%s, %d bytes)\n", field.Param, fileName, field.ContentType, len(content))
+		fields = append(fields, field)
+	}
+
+	request.SetMultipartFields(fields...)
+	return nil
+}
+
+// evaluateParams evaluates the parameters in the running context, and returns them as URL values
+func (r *testRunner) evaluateParams(params map[string]any) (url.Values, error) {
+	eval := evaluators.NewJsonXEvaluator(r.RunningContext)
+	values := make(url.Values, len(params))
+
+	for _, name := range maps.SortedKeys(params) {
+		paramValues, err := paramValues(eval.Evaluate(params[name]))
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s': %w", name, err)
+		}
+		values[eval.EvaluateStr(name)] = paramValues
+	}
+
+	return values, nil
+}
+
+// paramValues returns the text of an evaluated value, or of each item if it is a list. Objects are sent as JSON
+func paramValues(value any) ([]string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var plain any
+	if err := unmarshalKeepingNumbers(data, &plain); err != nil {
+		return nil, err
+	}
+
+	items, isList := plain.([]any)
+	if !isList {
+		items = []any{plain}
+	}
+
+	texts := make([]string, 0, len(items))
+	for _, item := range items {
+		text, err := paramText(item)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
+
+func paramText(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
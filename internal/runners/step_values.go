@@ -0,0 +1,72 @@
+package runners
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/totemcaf/test-by-example.git/internal/collections/maps"
+	"github.com/totemcaf/test-by-example.git/internal/contexts"
+	"github.com/totemcaf/test-by-example.git/internal/evaluators"
+)
+
+// valuesResolver evaluates the values of a step in the order of their dependencies. When a value uses another
+// value of the step, the other value is evaluated first, so the values can be declared in any order
+type valuesResolver struct {
+	contexts.RunningContext
+	values map[string]any
+	// set sets the evaluated value, in the step scope or in the flow context
+	set      func(name string, value any)
+	resolved map[string]bool
+	// resolving are the values being evaluated, the last one is the innermost
+	resolving []string
+	err       error
+}
+
+// evaluateValues evaluates the values in the context, and sets each one with set. A value that uses itself reads
+// the value of the context, other cycles are reported as an error
+func evaluateValues(context contexts.RunningContext, values map[string]any, set func(name string, value any)) error {
+	resolver := &valuesResolver{
+		RunningContext: context,
+		values:         values,
+		set:            set,
+		resolved:       make(map[string]bool, len(values)),
+	}
+
+	for _, name := range maps.SortedKeys(values) {
+		resolver.resolve(name)
+	}
+
+	return resolver.err
+}
+
+// Get returns the value with the given name, evaluating it first if it is a value of the step not evaluated yet
+func (v *valuesResolver) Get(name string) any {
+	if _, found := v.values[name]; found && !v.resolved[name] && v.err == nil {
+		v.resolve(name)
+	}
+	return v.RunningContext.Get(name)
+}
+
+func (v *valuesResolver) resolve(name string) {
+	if v.resolved[name] {
+		return
+	}
+
+	for i, resolving := range v.resolving {
+		if resolving != name {
+			continue
+		}
+		if i < len(v.resolving)-1 && v.err == nil {
+			cycle := append(v.resolving[i:], name)
+			v.err = fmt.Errorf("values have a cycle: %s", strings.Join(cycle, " -> "))
+		}
+		return
+	}
+
+	v.resolving = append(v.resolving, name)
+	value := evaluators.NewJsonXEvaluator(v).Evaluate(v.values[name])
+	v.resolving = v.resolving[:len(v.resolving)-1]
+
+	v.resolved[name] = true
+	v.set(name, value)
+}
@@ -1,20 +1,36 @@
 package runners
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/spf13/viper"
+	"github.com/totemcaf/test-by-example.git/internal/auth"
+	"github.com/totemcaf/test-by-example.git/internal/bodies"
+	"github.com/totemcaf/test-by-example.git/internal/collections/maps"
 	"github.com/totemcaf/test-by-example.git/internal/contexts"
 	"github.com/totemcaf/test-by-example.git/internal/evaluators"
 	"github.com/totemcaf/test-by-example.git/internal/model"
+	"github.com/totemcaf/test-by-example.git/internal/results"
 	"github.com/totemcaf/test-by-example.git/pkg/jsonx"
 	"go.uber.org/zap"
 )
 
 type TestRunner interface {
-	Run() error
+	Run() (*results.FlowResult, error)
+	// Skip returns the result of the flow when it is not run, with all its steps skipped
+	Skip() *results.FlowResult
 }
 
 type testRunner struct {
@@ -22,25 +38,237 @@ type testRunner struct {
 	contexts.RunningContext
 	client *resty.Client
 	logger *zap.SugaredLogger
+	out    io.Writer
+	// beforeRequest is called before sending each request
+	beforeRequest func()
+	// example is the position, starting at 1, of the flow example to run, or 0 if the flow has no examples
+	example int
+	// ctx cancels the flow, the teardown steps are run anyway
+	ctx context.Context
+	// initialValues are set in the context before the flow values
+	initialValues map[string]any
+	// timeout limits the time of the requests of the steps without timeout, 0 for no limit
+	timeout time.Duration
+	// authProviders are the providers of the auth of the flow and the flows run as steps, they keep the
+	// credentials obtained, e.g. OAuth2 tokens, and they can be shared with other runners
+	authProviders *auth.Providers
+	// tls is the global TLS configuration, the TLS of the flow is applied on top of it
+	tls *model.TLS
+	// flowTLS are the TLS configurations of the running flow, and of the flows that run it as a step, in the
+	// order they are applied
+	flowTLS []*model.TLS
 }
 
-func NewTestRunner(testFlow *model.TestFlow, logger *zap.SugaredLogger) *testRunner {
-	return &testRunner{
-		testFlow,
-		contexts.NewRunningContext(logger),
-		resty.New(),
-		logger,
+type Option func(runner *testRunner)
+
+// WithOutput sets where the requests and responses are printed, by default they are printed to the standard output
+func WithOutput(out io.Writer) Option {
+	return func(runner *testRunner) {
+		runner.out = out
+	}
+}
+
+// WithBeforeRequest sets a function called before sending each request, e.g. to limit the request rate
+func WithBeforeRequest(beforeRequest func()) Option {
+	return func(runner *testRunner) {
+		runner.beforeRequest = beforeRequest
+	}
+}
+
+// WithContext sets the context that interrupts the flow when it is cancelled
+func WithContext(ctx context.Context) Option {
+	return func(runner *testRunner) {
+		runner.ctx = ctx
+	}
+}
+
+// WithValues sets values in the flow context before the flow values, e.g. the values of the suite setup
+func WithValues(values map[string]any) Option {
+	return func(runner *testRunner) {
+		runner.initialValues = values
+	}
+}
+
+// WithTimeout sets the timeout of the requests of the steps that do not set their own timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(runner *testRunner) {
+		runner.timeout = timeout
+	}
+}
+
+// WithTLS sets the global TLS configuration of the requests, the flows can add their own configuration
+func WithTLS(tls *model.TLS) Option {
+	return func(runner *testRunner) {
+		runner.tls = tls
+	}
+}
+
+// WithAuthProviders sets the providers of the auth of the flows, so the credentials obtained, e.g. OAuth2 tokens,
+// are shared with the other runners that use them. By default, each runner has its own providers
+func WithAuthProviders(authProviders *auth.Providers) Option {
+	return func(runner *testRunner) {
+		runner.authProviders = authProviders
+	}
+}
+
+// withExample sets the flow example to run
+func withExample(example int) Option {
+	return func(runner *testRunner) {
+		runner.example = example
 	}
 }
 
-func (r *testRunner) Run() error {
+func NewTestRunner(testFlow *model.TestFlow, logger *zap.SugaredLogger, options ...Option) *testRunner {
+	runner := &testRunner{
+		testFlow:       testFlow,
+		RunningContext: contexts.NewRunningContext(logger),
+		client:         newClient(),
+		logger:         logger,
+		out:            os.Stdout,
+		beforeRequest:  func() {},
+		ctx:            context.Background(),
+		authProviders:  auth.NewProviders(),
+	}
+
+	for _, option := range options {
+		option(runner)
+	}
+
+	return runner
+}
+
+// NewTestRunners returns a runner for each example of the flow, or a single runner if the flow has no examples
+func NewTestRunners(testFlow *model.TestFlow, logger *zap.SugaredLogger, options ...Option) []TestRunner {
+	if testFlow.Spec.Examples == nil {
+		return []TestRunner{NewTestRunner(testFlow, logger, options...)}
+	}
+
+	var testRunners []TestRunner
+	for i := range testFlow.Spec.Examples.Rows {
+		exampleOptions := append([]Option{withExample(i + 1)}, options...)
+		testRunners = append(testRunners, NewTestRunner(testFlow, logger, exampleOptions...))
+	}
+	return testRunners
+}
+
+// newClient returns a client that decodes the JSON responses keeping the numbers as they are received
+func newClient() *resty.Client {
+	client := resty.New()
+	client.JSONUnmarshal = unmarshalKeepingNumbers
+	return client
+}
+
+// unmarshalKeepingNumbers decodes numbers as json.Number, so decimals are not rounded to float64
+func unmarshalKeepingNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// Run executes the flow steps, and returns the result of each step. The error is the cause of the flow failure.
+func (r *testRunner) Run() (*results.FlowResult, error) {
+	result := results.NewFlowResult(r.resultName())
+
+	r.flowTLS = []*model.TLS{r.testFlow.Spec.TLS}
+	if err := r.configureTLS(); err != nil {
+		result.Finish(err)
+		return result, err
+	}
+
 	r.initContext()
-	return r.runSteps(r.testFlow.Spec.Steps)
+	err := r.runPhases(r.testFlow.Spec, result)
+
+	result.Finish(err)
+	return result, err
+}
+
+// Skip returns the result of the flow when it is not run, e.g. when the run stops after a failed flow
+func (r *testRunner) Skip() *results.FlowResult {
+	result := results.NewFlowResult(r.resultName())
+	spec := r.testFlow.Spec
+
+	result.StartPhase(results.Setup)
+	skipSteps(spec.Setup, result)
+	result.StartPhase(results.Main)
+	skipSteps(spec.Steps, result)
+	result.StartPhase(results.Teardown)
+	skipSteps(spec.Teardown, result)
+
+	result.Skip()
+	return result
+}
+
+// resultName returns the name of the flow, with the number of its example if it runs one
+func (r *testRunner) resultName() string {
+	if r.example > 0 {
+		return model.ExampleName(r.testFlow.Metadata.Name, r.example)
+	}
+	return r.testFlow.Metadata.Name
+}
+
+// runPhases runs the setup steps, the flow steps if the setup succeeds, and the teardown steps. The teardown
+// steps run even if the flow fails, is interrupted or times out.
+func (r *testRunner) runPhases(spec model.TestFlowSpec, flowResult *results.FlowResult) error {
+	if spec.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.ctx, spec.Timeout)
+		parent := r.ctx
+		r.ctx = ctx
+		defer func() {
+			cancel()
+			r.ctx = parent
+		}()
+	}
+
+	flowResult.StartPhase(results.Setup)
+	err := r.runSteps(spec.Setup, flowResult)
+
+	flowResult.StartPhase(results.Main)
+	if err != nil {
+		err = fmt.Errorf("setup failed: %w", err)
+		skipSteps(spec.Steps, flowResult)
+	} else {
+		err = r.runSteps(spec.Steps, flowResult)
+	}
+
+	flowResult.StartPhase(results.Teardown)
+	if teardownErr := r.runTeardown(spec.Teardown, flowResult); teardownErr != nil && err == nil {
+		err = fmt.Errorf("teardown failed: %w", teardownErr)
+	}
+
+	return err
+}
+
+// runTeardown runs all the teardown steps, even if some of them fail. They are run even if the flow was
+// interrupted, with the values extracted before.
+func (r *testRunner) runTeardown(steps []model.StepSpec, flowResult *results.FlowResult) error {
+	ctx := r.ctx
+	r.ctx = context.Background()
+	defer func() {
+		r.ctx = ctx
+	}()
+
+	var failedSteps []string
+
+	for _, step := range steps {
+		if err := r.runStepExamples(step, flowResult); err != nil {
+			r.logger.Errorf("Teardown step '%s' failed: %s", step.NameOrUrl(), err.Error())
+			failedSteps = append(failedSteps, step.NameOrUrl())
+		}
+	}
+
+	if len(failedSteps) > 0 {
+		return fmt.Errorf("steps failed: %s", strings.Join(failedSteps, ", "))
+	}
+	return nil
 }
 
 func (r *testRunner) initContext() {
 	r.initEnvironmentVars()
+	r.setValues(r.initialValues)
 	r.initValues()
+	if r.example > 0 {
+		r.setValues(r.testFlow.Spec.Examples.Rows[r.example-1])
+	}
 }
 
 func (r *testRunner) initEnvironmentVars() {
@@ -50,61 +278,585 @@ func (r *testRunner) initEnvironmentVars() {
 }
 
 func (r *testRunner) initValues() {
-	for name, value := range r.testFlow.Spec.Values {
+	r.setValues(r.testFlow.Spec.Values)
+}
+
+func (r *testRunner) setValues(values map[string]any) {
+	for name, value := range values {
 		r.Set(name, value)
 	}
 }
 
-func (r *testRunner) runSteps(steps []model.StepSpec) error {
-	for _, step := range steps {
-		var stepRef *model.StepSpec
-		if step.IsReference() {
-			var found bool
-			stepRef, found = r.testFlow.GetGlobalStepSpec(step.NameOrUrl())
-			if !found {
-				return fmt.Errorf("step '%s' not found", step.NameOrUrl())
-			}
-		} else {
-			stepRef = &step
+// runSteps runs the steps in order. A failed step aborts the flow, unless it continues on error.
+// In that case, the flow continues and fails at the end.
+func (r *testRunner) runSteps(steps []model.StepSpec, flowResult *results.FlowResult) error {
+	var failedSteps []string
+
+	for i, step := range steps {
+		if err := r.ctx.Err(); err != nil {
+			skipSteps(steps[i:], flowResult)
+			return interruptionError(err)
+		}
+
+		err := r.runStepExamples(step, flowResult)
+
+		if err != nil && step.ContinueOnError {
+			r.logger.Warnf("Step '%s' failed, continuing: %s", step.NameOrUrl(), err.Error())
+			failedSteps = append(failedSteps, step.NameOrUrl())
+			continue
 		}
 
-		if err := r.runStep(stepRef); err != nil {
+		if err != nil {
+			skipSteps(steps[i+1:], flowResult)
 			return err
 		}
 	}
+
+	if len(failedSteps) > 0 {
+		return fmt.Errorf("steps failed: %s", strings.Join(failedSteps, ", "))
+	}
 	return nil
 }
 
-func (r *testRunner) runStep(step *model.StepSpec) error {
-	r.logger.Infof("Running '%s'%s", step.NameOrUrl(), referenceType(step))
-	request := r.client.R()
+// interruptionError returns the cause of the flow interruption from the error of its context
+func interruptionError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("flow timed out")
+	}
+	return fmt.Errorf("interrupted")
+}
 
-	if err := r.setHeaders(request, step.Headers); err != nil {
+func skipSteps(steps []model.StepSpec, flowResult *results.FlowResult) {
+	for _, step := range steps {
+		flowResult.SkipStep(step.NameOrUrl())
+	}
+}
+
+// runStepExamples runs the step once for each of its examples, with the values of the example in the step
+// scope, or once if the step has no examples. All the examples are run, even if some of them fail.
+func (r *testRunner) runStepExamples(step model.StepSpec, flowResult *results.FlowResult) error {
+	examples := r.stepExamples(step)
+	if examples == nil {
+		return r.runStepAs(step.NameOrUrl(), step, nil, flowResult)
+	}
+
+	var failedExamples []string
+
+	for i, row := range examples.Rows {
+		name := model.ExampleName(step.NameOrUrl(), i+1)
+
+		if err := r.runStepAs(name, step, row, flowResult); err != nil {
+			r.logger.Errorf("Step '%s' failed: %s", name, err.Error())
+			failedExamples = append(failedExamples, name)
+		}
+	}
+
+	if len(failedExamples) > 0 {
+		return fmt.Errorf("examples failed: %s", strings.Join(failedExamples, ", "))
+	}
+	return nil
+}
+
+// stepExamples returns the examples of the step, or the ones of the global step it references
+func (r *testRunner) stepExamples(step model.StepSpec) *model.Examples {
+	if step.Examples == nil && step.IsReference() {
+		if stepRef, found := r.testFlow.GetGlobalStepSpec(step.NameOrUrl()); found {
+			return stepRef.Examples
+		}
+	}
+	return step.Examples
+}
+
+// runStepAs runs the step with the given name, and the values of its example, if any, in the step scope
+func (r *testRunner) runStepAs(name string, step model.StepSpec, example map[string]any, flowResult *results.FlowResult) error {
+	stepResult := flowResult.StartStep(name)
+
+	err := r.runStepSpec(step, example, stepResult)
+	stepResult.Finish(err)
+
+	return err
+}
+
+// runStepSpec runs the step, or the global step it references
+func (r *testRunner) runStepSpec(step model.StepSpec, example map[string]any, result *results.StepResult) error {
+	if step.IsFlow() {
+		return r.runFlowStep(step, example, result)
+	}
+
+	var stepRef *model.StepSpec
+	if step.IsReference() {
+		var found bool
+		stepRef, found = r.testFlow.GetGlobalStepSpec(step.NameOrUrl())
+		if !found {
+			return fmt.Errorf("step '%s' not found", step.NameOrUrl())
+		}
+	} else {
+		stepRef = &step
+	}
+
+	scopeSteps := []*model.StepSpec{stepRef}
+	if step.IsReference() {
+		scopeSteps = append(scopeSteps, &step)
+	}
+
+	flowContext := r.RunningContext
+	scope, err := r.enterStepScope(step, example, scopeSteps...)
+	defer func() {
+		r.RunningContext = flowContext
+	}()
+	if err != nil {
 		return err
 	}
-	if err := r.setBody(request, step.Body); err != nil {
+
+	poll := step.Poll
+	if poll == nil {
+		poll = stepRef.Poll
+	}
+
+	options := requestOptions{
+		poll:    poll,
+		retry:   step.Retry.Or(stepRef.Retry).Or(r.testFlow.Spec.Retry).Or(model.DefaultRetry),
+		timeout: firstPositive(step.Timeout, stepRef.Timeout, r.timeout),
+	}
+
+	if err := r.pollStep(stepRef, options, result); err != nil {
 		return err
 	}
 
-	var resultBody map[string]any
+	return setOutputs(step.Outputs, scope, flowContext)
+}
+
+// requestOptions configure how the request of a step is sent, from the step, the referenced step and the flow
+type requestOptions struct {
+	poll    *model.Poll
+	retry   model.Retry
+	timeout time.Duration
+}
 
-	err := r.setResult(request, &resultBody)
+func firstPositive(durations ...time.Duration) time.Duration {
+	for _, duration := range durations {
+		if duration > 0 {
+			return duration
+		}
+	}
+	return 0
+}
+
+// enterStepScope creates a new scope for the step, with the values of the example, and the arguments evaluated in
+// the flow context and the example, and evaluates the values of the steps in order. The values of a step that
+// promotes its values are set in the flow context instead. If the step is isolated, the values set by the step,
+// e.g. extracted values, are kept in the scope. The running context is the scope, even if it returns an error.
+func (r *testRunner) enterStepScope(step model.StepSpec, example map[string]any, steps ...*model.StepSpec) (*contexts.ScopedContext, error) {
+	flowContext := r.RunningContext
+	var scope *contexts.ScopedContext
+
+	if step.IsIsolated() {
+		scope = contexts.NewIsolatedContext(flowContext)
+	} else {
+		scope = contexts.NewScopedContext(flowContext)
+	}
 
+	for name, value := range example {
+		scope.SetLocal(name, value)
+	}
+
+	// The arguments are evaluated before setting them, so they do not see each other
+	argumentsEval := evaluators.NewJsonXEvaluator(scope)
+	arguments := make(map[string]any, len(step.With))
+	for _, name := range maps.SortedKeys(step.With) {
+		arguments[name] = argumentsEval.Evaluate(step.With[name])
+	}
+	for name, value := range arguments {
+		scope.SetLocal(name, value)
+	}
+
+	r.RunningContext = scope
+
+	for _, step := range steps {
+		set := scope.SetLocal
+		if step.PromoteValues {
+			set = flowContext.Set
+		}
+		if err := evaluateValues(scope, step.Values, set); err != nil {
+			return scope, fmt.Errorf("step '%s': %w", step.NameOrUrl(), err)
+		}
+	}
+
+	return scope, nil
+}
+
+// runFlowStep runs the steps of another flow, with the context of this flow or in its own scope. The results
+// of the flow steps are nested in the step result
+func (r *testRunner) runFlowStep(step model.StepSpec, example map[string]any, result *results.StepResult) error {
+	subFlow, found := r.testFlow.GetTestFlow(*step.Flow)
+	if !found {
+		return fmt.Errorf("flow '%s' not found", *step.Flow)
+	}
+
+	r.logger.Infof("Running flow '%s'", subFlow.Metadata.Name)
+
+	testFlow, flowContext, logger, client, flowTLS := r.testFlow, r.RunningContext, r.logger, r.client, r.flowTLS
+	scope, err := r.enterStepScope(step, example, &step)
+	defer func() {
+		r.testFlow, r.RunningContext, r.logger, r.client, r.flowTLS = testFlow, flowContext, logger, client, flowTLS
+	}()
 	if err != nil {
 		return err
 	}
 
+	r.testFlow = subFlow
+	r.logger = logger.Named(subFlow.Metadata.Name)
+	r.initDefaults()
+
+	// The TLS of the flow is added to the one of the running flow, in a new client so the running flow keeps its own
+	if subFlow.Spec.TLS != nil {
+		r.client = newClient()
+		r.flowTLS = append(flowTLS[:len(flowTLS):len(flowTLS)], subFlow.Spec.TLS)
+		if err = r.configureTLS(); err != nil {
+			return fmt.Errorf("flow '%s': %w", subFlow.Metadata.Name, err)
+		}
+	}
+
+	if err = r.runSubFlow(subFlow, result); err != nil {
+		return fmt.Errorf("flow '%s' failed: %w", subFlow.Metadata.Name, err)
+	}
+
+	return setOutputs(step.Outputs, scope, flowContext)
+}
+
+// initDefaults sets the environment variables and the values of a flow run as a step, except the ones already
+// set, so the values of the calling flow and the arguments of the step are kept
+func (r *testRunner) initDefaults() {
+	values := r.Values()
+	setDefault := func(name string, value any) {
+		if _, found := values[name]; !found {
+			r.Set(name, value)
+		}
+	}
+
+	for name, envVarName := range r.testFlow.Spec.Environment {
+		setDefault(name, viper.GetString(envVarName))
+	}
+	for name, value := range r.testFlow.Spec.Values {
+		setDefault(name, value)
+	}
+}
+
+// runSubFlow runs the phases of a flow run as a step, once for each of its examples, with the values of the
+// example in its own scope, or once if the flow has no examples. The results of each example are nested in a
+// step of the result. All the examples are run, even if some of them fail.
+func (r *testRunner) runSubFlow(subFlow *model.TestFlow, result *results.StepResult) error {
+	if subFlow.Spec.Examples == nil {
+		subFlowResult := results.NewFlowResult(subFlow.Metadata.Name)
+		err := r.runPhases(subFlow.Spec, subFlowResult)
+		result.Steps = subFlowResult.Steps
+		return err
+	}
+
+	scope := r.RunningContext
+	defer func() {
+		r.RunningContext = scope
+	}()
+
+	examplesResult := results.NewFlowResult(subFlow.Metadata.Name)
+	var failedExamples []string
+
+	for i, row := range subFlow.Spec.Examples.Rows {
+		name := model.ExampleName(subFlow.Metadata.Name, i+1)
+
+		exampleScope := contexts.NewScopedContext(scope)
+		for name, value := range row {
+			exampleScope.SetLocal(name, value)
+		}
+		r.RunningContext = exampleScope
+
+		exampleResult := examplesResult.StartStep(name)
+		subFlowResult := results.NewFlowResult(name)
+		err := r.runPhases(subFlow.Spec, subFlowResult)
+		exampleResult.Steps = subFlowResult.Steps
+		exampleResult.Finish(err)
+
+		if err != nil {
+			r.logger.Errorf("Flow '%s' failed: %s", name, err.Error())
+			failedExamples = append(failedExamples, name)
+		}
+	}
+
+	result.Steps = examplesResult.Steps
+
+	if len(failedExamples) > 0 {
+		return fmt.Errorf("examples failed: %s", strings.Join(failedExamples, ", "))
+	}
+	return nil
+}
+
+// setOutputs sets in the flow context the values of the step scope declared as outputs. Only the values set in
+// the scope are read, a value of the flow with the same name is not an output of the step
+func setOutputs(outputs map[string]string, scope *contexts.ScopedContext, flowContext contexts.RunningContext) error {
+	for _, name := range maps.SortedKeys(outputs) {
+		value, found := scope.GetLocal(outputs[name])
+		if !found {
+			return fmt.Errorf("output '%s' not found, expected value '%s'", name, outputs[name])
+		}
+		flowContext.Set(name, value)
+	}
+	return nil
+}
+
+// mismatchError is the error of a response different from the expected one, e.g. by its status code or its body
+type mismatchError struct {
+	err error
+}
+
+func (e *mismatchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *mismatchError) Unwrap() error {
+	return e.err
+}
+
+// pollStep runs the step until the response matches the expected one, or the poll timeout expires. The result
+// has the response and the differences of the last request. Other errors, e.g. network errors, fail the step
+// without polling again. Without poll, the step is run once.
+func (r *testRunner) pollStep(step *model.StepSpec, options requestOptions, result *results.StepResult) error {
+	poll := options.poll
+	if poll == nil {
+		return r.runStep(step, options, result)
+	}
+
+	deadline := time.Now().Add(poll.Timeout)
+	interval := poll.FirstInterval()
+
+	for attempts := 1; ; attempts++ {
+		err := r.runStep(step, options, result)
+
+		var mismatch *mismatchError
+		if !errors.As(err, &mismatch) {
+			return err
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("poll timed out after %d attempts: %w", attempts, err)
+		}
+
+		r.logger.Infof("Step '%s' does not match yet, polling again in %s", step.NameOrUrl(), interval)
+
+		if !r.wait(interval) {
+			return err
+		}
+
+		interval = poll.NextInterval(interval)
+	}
+}
+
+// runStep sends the step request, and checks the response. The request is sent again if it fails by an error, or
+// with a status code, that the retry allows. Each request sent is recorded in the result attempts.
+func (r *testRunner) runStep(step *model.StepSpec, options requestOptions, result *results.StepResult) error {
+	r.logger.Infof("Running '%s'%s", step.NameOrUrl(), referenceType(step))
+	retry := options.retry
+	interval := retry.Interval
+
+	renewed := false
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		result.Response, result.Differences = nil, nil
+
+		response, err := r.send(step, options.timeout, result)
+
+		// A request rejected with expired credentials is sent again once, it does not count as a retry
+		if err == nil && !renewed && r.renewCredentials(step, response) {
+			renewed = true
+			result.AddAttempt(start, fmt.Errorf("received status %d, renewing the credentials", response.StatusCode()))
+			attempt--
+			continue
+		}
+
+		retryErr := retryError(retry, step, response, err)
+		if retryErr == nil || attempt >= retry.Attempts || !retry.Retries(step.Method()) || r.ctx.Err() != nil {
+			if err == nil {
+				err = r.processResult(response, step, result)
+			}
+			result.AddAttempt(start, err)
+			return err
+		}
+
+		result.AddAttempt(start, retryErr)
+		r.logger.Warnf("Step '%s' failed (attempt %d of %d), retrying in %s: %s", step.NameOrUrl(), attempt, retry.Attempts, interval, retryErr.Error())
+
+		if !r.wait(interval) {
+			return retryErr
+		}
+
+		interval = retry.NextInterval(interval)
+	}
+}
+
+// authProvider returns the auth provider of the running flow, or nil if the flow has no auth
+func (r *testRunner) authProvider() auth.Provider {
+	return r.authProviders.Get(r.testFlow.Spec.Auth, r.client)
+}
+
+// renewCredentials returns true if the response is 401 Unauthorized, not expected by the step, and the
+// credentials of the flow auth can be renewed
+func (r *testRunner) renewCredentials(step *model.StepSpec, response *resty.Response) bool {
+	if response.StatusCode() != http.StatusUnauthorized {
+		return false
+	}
+	if step.Response != nil && step.Response.StatusCode == http.StatusUnauthorized {
+		return false
+	}
+
+	provider := r.authProvider()
+	if provider == nil || !provider.Renew(r.RunningContext) {
+		return false
+	}
+
+	r.logger.Infof("Step '%s' was not authorized, renewing the credentials", step.NameOrUrl())
+	return true
+}
+
+// wait waits for the given time, it returns false if the flow is interrupted before
+func (r *testRunner) wait(interval time.Duration) bool {
+	select {
+	case <-time.After(interval):
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
+
+// send builds the step request and sends it, and returns the response. The request is cancelled if the timeout,
+// if any, expires before the response is read
+func (r *testRunner) send(step *model.StepSpec, timeout time.Duration, result *results.StepResult) (*resty.Response, error) {
+	ctx := r.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	request := r.client.R().SetContext(ctx)
+
+	if provider := r.authProvider(); provider != nil {
+		if err := provider.Authenticate(request, r.RunningContext); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.setHeaders(request, step.Headers); err != nil {
+		return nil, err
+	}
+	if err := r.setQuery(request, step.Query); err != nil {
+		return nil, err
+	}
+	if err := r.setBody(request, step.Body); err != nil {
+		return nil, err
+	}
+	if err := r.setForm(request, step.Form); err != nil {
+		return nil, err
+	}
+	if err := r.setMultipart(request, step.Multipart); err != nil {
+		return nil, err
+	}
+
 	response, err := r.execute(request, step)
 
+	result.Request = requestResult(request)
+
+	if err != nil && r.ctx.Err() != nil {
+		return nil, fmt.Errorf("%v: %w", interruptionError(r.ctx.Err()), err)
+	}
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("request timed out after %s: %w", timeout, err)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := r.processResult(response, resultBody, step); err != nil {
-		return err
+	result.Response = responseResult(response, nil)
+
+	return response, nil
+}
+
+// responseFormat returns the format of the step response, or the one of the response Content-Type if the step
+// does not set it
+func responseFormat(response *resty.Response, step *model.StepSpec) string {
+	if step.Response != nil && step.Response.Format != "" {
+		return step.Response.Format
 	}
+	return bodies.Format(response.Header().Get("Content-Type"), response.Body())
+}
 
-	return err
+// retryError returns why the request can be retried, or nil if the retry does not allow to send it again
+func retryError(retry model.Retry, step *model.StepSpec, response *resty.Response, err error) error {
+	if err != nil {
+		if kind := networkErrorKind(err); kind != "" && retry.RetriesError(kind) {
+			return err
+		}
+		return nil
+	}
+
+	statusCode := response.StatusCode()
+	if step.Response != nil && statusCode != step.Response.StatusCode && retry.RetriesStatus(statusCode) {
+		return fmt.Errorf("received status %d", statusCode)
+	}
+	return nil
+}
+
+// networkErrorKind returns the kind of network error, as used in the retry errors, or "" if the error is not
+// a network error
+func networkErrorKind(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return ""
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return model.RetryTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return model.RetryConnection
+	}
+
+	return ""
+}
+
+func requestResult(request *resty.Request) *results.Request {
+	headers := make(map[string]string, len(request.Header))
+	for name := range request.Header {
+		headers[name] = request.Header.Get(name)
+	}
+
+	// The URL sent has the query parameters
+	requestURL := request.URL
+	if request.RawRequest != nil {
+		requestURL = request.RawRequest.URL.String()
+	}
+
+	var body any = request.Body
+	if body == nil && len(request.FormData) > 0 {
+		body = request.FormData
+	}
+
+	return &results.Request{
+		Method:  request.Method,
+		URL:     requestURL,
+		Headers: headers,
+		Body:    body,
+	}
+}
+
+func responseResult(response *resty.Response, body any) *results.Response {
+	return &results.Response{
+		StatusCode: response.StatusCode(),
+		Headers:    response.Header(),
+		Body:       body,
+		Duration:   response.Time(),
+	}
 }
 
 func referenceType(step *model.StepSpec) string {
@@ -120,7 +872,7 @@ func (r *testRunner) setHeaders(request *resty.Request, headers map[string]strin
 
 		name := eval.EvaluateStr(key)
 		valueStr := eval.EvaluateStr(value)
-		fmt.Printf("Using Header: %s: %s\n", name, valueStr)
+		fmt.Fprintf(r.out, "Using Header: %s: %s\n", name, valueStr)
 		request.SetHeader(name, valueStr)
 	}
 
@@ -128,6 +880,10 @@ func (r *testRunner) setHeaders(request *resty.Request, headers map[string]strin
 }
 
 func (r *testRunner) setBody(request *resty.Request, body *model.Json) error {
+	if body == nil {
+		return nil
+	}
+
 	parser := jsonx.NewParser()
 
 	jsonXBody := parser.Parse(body)
@@ -139,7 +895,7 @@ func (r *testRunner) setBody(request *resty.Request, body *model.Json) error {
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("\nUsing Body: %s\n\n", jsonBody)
+	fmt.Fprintf(r.out, "\nUsing Body: %s\n\n", jsonBody)
 	request.SetBody(finalBody)
 	return nil
 
@@ -149,28 +905,126 @@ func (r *testRunner) execute(request *resty.Request, step *model.StepSpec) (*res
 	eval := evaluators.NewJsonXEvaluator(r.RunningContext)
 	url := eval.EvaluateStr(step.Url())
 
+	r.beforeRequest()
 	return request.Execute(step.Method(), url)
 }
 
-func (r *testRunner) processResult(response *resty.Response, actualBody map[string]any, step *model.StepSpec) error {
+// processResult checks the response, the errors of a response different from the expected one are a mismatchError.
+// The body is only decoded if the step expects a body or a format, and the status code is checked before the body
+// can fail to be decoded
+func (r *testRunner) processResult(response *resty.Response, step *model.StepSpec, result *results.StepResult) error {
+	var actualBody any
+	var decodeErr error
+	if step.Response.Body != nil || step.Response.Format != "" {
+		actualBody, decodeErr = bodies.Decode(responseFormat(response, step), response.Body())
+		result.Response.Body = actualBody
+	}
+
 	if err := r.checkResponseCode(response, step); err != nil {
+		return &mismatchError{err}
+	}
+
+	if err := checkDuration(response, step); err != nil {
+		return &mismatchError{err}
+	}
+
+	if err := r.checkHeadersAndCookies(response, step, result); err != nil {
 		return err
 	}
 
-	differ := jsonx.NewDiffer(r.RunningContext)
+	if decodeErr != nil {
+		return decodeErr
+	}
+
+	// Without an expected body, the response body is not checked
+	if step.Response.Body == nil {
+		return nil
+	}
+
+	comparison := step.Response.Comparison.Or(r.testFlow.Spec.Comparison)
+	options := comparison.DiffOptions()
+	if responseFormat(response, step) == model.FormatBinary {
+		// Binary bodies can be checked by their size, their hash, or both
+		options = append(options, jsonx.WithMode(jsonx.MatchContains))
+	}
+	differ := jsonx.NewDiffer(r.RunningContext, options...)
 
 	if jsonStr, err := json.Marshal(actualBody); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(r.out, err)
 	} else {
-		fmt.Printf("Actual Body Result: %s\n\n", jsonStr)
+		fmt.Fprintf(r.out, "Actual Body Result: %s\n\n", jsonStr)
 	}
 
-	return differ.Compare(step.Response.Body, actualBody)
+	return compare(differ, step.Response.Body, actualBody, result)
+}
+
+// compare records the differences between the expected and the actual values in the result, and returns them as a
+// mismatchError. Other errors, e.g. an invalid expected value, are returned as they are
+func compare(differ *jsonx.Differ, expected any, actual any, result *results.StepResult) error {
+	err := differ.Compare(expected, actual)
+	result.Differences = append(result.Differences, differ.Differences()...)
+
+	if err != nil && len(differ.Differences()) > 0 {
+		return &mismatchError{err}
+	}
+	return err
 }
 
-func (r *testRunner) setResult(request *resty.Request, m *map[string]any) error {
-	request.SetResult(m)
+// checkHeadersAndCookies compares the expected headers and cookies with the ones in the response.
+// Headers and cookies not expected are not checked.
+func (r *testRunner) checkHeadersAndCookies(response *resty.Response, step *model.StepSpec, result *results.StepResult) error {
+	if len(step.Response.Headers) == 0 && len(step.Response.Cookies) == 0 {
+		return nil
+	}
 
+	expected := make(map[string]any)
+	actual := make(map[string]any)
+
+	if len(step.Response.Headers) > 0 {
+		expected["headers"] = step.Response.Headers
+		actual["headers"] = actualHeaders(response, step.Response.Headers)
+	}
+
+	if len(step.Response.Cookies) > 0 {
+		expected["cookies"] = step.Response.Cookies
+		actual["cookies"] = actualCookies(response)
+	}
+
+	differ := jsonx.NewDiffer(r.RunningContext, jsonx.WithMode(jsonx.MatchContains))
+
+	return compare(differ, expected, actual, result)
+}
+
+// actualHeaders returns the values of the expected headers found in the response. Header names are
+// case-insensitive, so they are returned with the name used in the expected headers
+func actualHeaders(response *resty.Response, expected model.Headers) map[string]string {
+	headers := make(map[string]string)
+
+	for name := range expected {
+		if values := response.Header().Values(name); len(values) > 0 {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+
+	return headers
+}
+
+func actualCookies(response *resty.Response) map[string]string {
+	cookies := make(map[string]string)
+
+	for _, cookie := range response.Cookies() {
+		cookies[cookie.Name] = cookie.Value
+	}
+
+	return cookies
+}
+
+// checkDuration fails if the response was received after the maximum duration of the step, if any
+func checkDuration(response *resty.Response, step *model.StepSpec) error {
+	maxDuration := step.Response.MaxDuration
+	if maxDuration > 0 && response.Time() > maxDuration {
+		return fmt.Errorf("[%s] expected response in %s, received in %s", step.NameOrUrl(), maxDuration, response.Time())
+	}
 	return nil
 }
 
@@ -0,0 +1,1124 @@
+package runners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"github.com/totemcaf/test-by-example.git/internal/results"
+	"go.uber.org/zap"
+)
+
+func newTestFlow(steps ...model.StepSpec) *model.TestFlow {
+	return &model.TestFlow{
+		ApiVersion: model.ApiVersion,
+		Kind:       model.TestFlowKind,
+		Metadata:   model.Metadata{Name: "test-flow"},
+		Spec:       model.TestFlowSpec{Steps: steps},
+	}
+}
+
+// newNamedFlow returns a flow with the given name, e.g. to run it as a step of another flow
+func newNamedFlow(name string, steps ...model.StepSpec) *model.TestFlow {
+	flow := newTestFlow(steps...)
+	flow.Metadata.Name = name
+	return flow
+}
+
+// inCollection returns the flow from a collection with the global steps and the other flows, so it can use them
+func inCollection(flow *model.TestFlow, globalSteps map[string]*model.Step, flows ...*model.TestFlow) *model.TestFlow {
+	collection := &model.TestFlowCollection{
+		Flows:       map[string]*model.TestFlow{flow.Metadata.Name: flow},
+		GlobalSteps: globalSteps,
+	}
+	for _, other := range flows {
+		collection.Flows[other.Metadata.Name] = other
+	}
+	flow, _ = collection.GetTestFlow(flow.Metadata.Name)
+	return flow
+}
+
+// getStep returns a step that gets the url, and expects a 200 status
+func getStep(name string, url string) model.StepSpec {
+	return model.StepSpec{Name: asPointer(name), Get: asPointer(url), Response: &model.Response{StatusCode: 200}}
+}
+
+func newServer(handler http.HandlerFunc) *httptest.Server {
+	return httptest.NewServer(handler)
+}
+
+// recordingServer records the paths requested, and responds with its handler
+type recordingServer struct {
+	*httptest.Server
+	lock  sync.Mutex
+	paths []string
+}
+
+func newRecordingServer(handler http.HandlerFunc) *recordingServer {
+	server := &recordingServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.lock.Lock()
+		server.paths = append(server.paths, r.URL.Path)
+		server.lock.Unlock()
+		handler(w, r)
+	}))
+	return server
+}
+
+// Paths returns the paths requested since the server started or was reset
+func (s *recordingServer) Paths() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.paths
+}
+
+func (s *recordingServer) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.paths = nil
+}
+
+// respondJSON returns a handler that responds the JSON body
+func respondJSON(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// respondOK is a handler that responds 200 without body
+func respondOK(http.ResponseWriter, *http.Request) {}
+
+func makeLogger() *zap.SugaredLogger {
+	return zap.NewNop().Sugar()
+}
+
+func asPointer[T any](t T) *T {
+	return &t
+}
+
+func Test_run_checks_and_extracts_headers_and_cookies(t *testing.T) {
+	// GIVEN a server that responds with headers and cookies
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/clients/123")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		_, _ = w.Write([]byte(`{"id": 123}`))
+	})
+	defer server.Close()
+
+	step := model.StepSpec{
+		Post: asPointer(server.URL + "/clients"),
+		Response: &model.Response{
+			StatusCode: 200,
+			Headers: model.Headers{
+				"location":     "$(location)",
+				"Content-Type": "$(:/application\\/json.*/)",
+			},
+			Cookies: map[string]string{"session": "$(session)"},
+			Body:    &model.Json{"id": 123},
+		},
+	}
+	runner := NewTestRunner(newTestFlow(step), makeLogger())
+
+	// WHEN the flow runs
+	_, err := runner.Run()
+
+	// THEN the headers and cookies are checked and extracted
+	assert.NoError(t, err)
+	assert.Equal(t, "/clients/123", runner.Get("location").(fmt.Stringer).String())
+	assert.Equal(t, "abc", runner.Get("session").(fmt.Stringer).String())
+}
+
+func Test_run_fails_when_header_is_missing_or_different(t *testing.T) {
+	// GIVEN a server that responds without the expected header
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	defer server.Close()
+
+	step := model.StepSpec{
+		Get: asPointer(server.URL),
+		Response: &model.Response{
+			StatusCode: 200,
+			Headers:    model.Headers{"Cache-Control": "no-store", "Location": "$(:any)"},
+			Body:       &model.Json{},
+		},
+	}
+	runner := NewTestRunner(newTestFlow(step), makeLogger())
+
+	// WHEN the flow runs
+	_, err := runner.Run()
+
+	// THEN the differences are reported
+	assert.ErrorContains(t, err, "headers.Cache-Control: different")
+	assert.ErrorContains(t, err, "headers.Location: missing value")
+}
+
+func Test_run_does_not_check_the_body_when_none_is_expected(t *testing.T) {
+	// GIVEN a server that responds a body
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 123}`))
+	})
+	defer server.Close()
+
+	// AND a step without expected body, and another one expecting an empty object
+	withoutBody := model.StepSpec{Get: asPointer(server.URL), Response: &model.Response{StatusCode: 200}}
+	emptyBody := model.StepSpec{Get: asPointer(server.URL), Response: &model.Response{StatusCode: 200, Body: &model.Json{}}}
+
+	// WHEN the flows run
+	_, withoutBodyErr := NewTestRunner(newTestFlow(withoutBody), makeLogger()).Run()
+	_, emptyBodyErr := NewTestRunner(newTestFlow(emptyBody), makeLogger()).Run()
+
+	// THEN only the expected body is compared
+	assert.NoError(t, withoutBodyErr)
+	assert.ErrorContains(t, emptyBodyErr, "id: extra value")
+}
+
+func Test_run_returns_step_results(t *testing.T) {
+	// GIVEN a server that responds a different body
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "James", "amount": 2200.40}`))
+	})
+	defer server.Close()
+
+	flow := newTestFlow(
+		model.StepSpec{
+			Name:    asPointer("create"),
+			Post:    asPointer(server.URL + "/clients"),
+			Headers: model.Headers{"Api-Key": "secret"},
+			Body:    &model.Json{"name": "Chrisjen"},
+			Response: &model.Response{
+				StatusCode: 200,
+				Body:       &model.Json{"name": "Chrisjen", "amount": 2200.40},
+			},
+		},
+		getStep("get", server.URL+"/clients/1"),
+	)
+	runner := NewTestRunner(flow, makeLogger())
+
+	// WHEN the flow runs
+	result, err := runner.Run()
+
+	// THEN the result has the request, the response and the differences of the failed step
+	assert.Error(t, err)
+	assert.Equal(t, results.Failed, result.Status)
+	assert.Len(t, result.Steps, 2)
+
+	step := result.Steps[0]
+	assert.Equal(t, "create", step.Name)
+	assert.Equal(t, results.Failed, step.Status)
+	assert.Equal(t, "POST", step.Request.Method)
+	assert.Equal(t, server.URL+"/clients", step.Request.URL)
+	assert.Equal(t, "secret", step.Request.Headers["Api-Key"])
+	assert.Equal(t, 200, step.Response.StatusCode)
+	assert.Equal(t, map[string]any{"name": "James", "amount": json.Number("2200.40")}, step.Response.Body)
+	assert.Len(t, step.Differences, 1)
+	assert.Equal(t, "different", step.Differences[0].Message)
+
+	assert.Equal(t, results.StepResult{Name: "get", Status: results.Skipped}, *result.Steps[1])
+}
+
+func Test_run_continues_after_step_that_continues_on_error(t *testing.T) {
+	// GIVEN a server that responds a different body
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "James"}`))
+	})
+	defer server.Close()
+
+	flow := newTestFlow(
+		model.StepSpec{
+			Name:            asPointer("flaky"),
+			Get:             asPointer(server.URL + "/clients/1"),
+			Response:        &model.Response{StatusCode: 200, Body: &model.Json{"name": "Chrisjen"}},
+			ContinueOnError: true,
+		},
+		model.StepSpec{
+			Name:     asPointer("get"),
+			Get:      asPointer(server.URL + "/clients/1"),
+			Response: &model.Response{StatusCode: 200, Body: &model.Json{"name": "James"}},
+		},
+	)
+	runner := NewTestRunner(flow, makeLogger())
+
+	// WHEN the flow runs
+	result, err := runner.Run()
+
+	// THEN the next step is executed, but the flow fails
+	assert.EqualError(t, err, "steps failed: flaky")
+	assert.Equal(t, results.Failed, result.Status)
+	assert.Len(t, result.Steps, 2)
+	assert.Equal(t, results.Failed, result.Steps[0].Status)
+	assert.Len(t, result.Steps[0].Differences, 1)
+	assert.Equal(t, results.Passed, result.Steps[1].Status)
+}
+
+func Test_run_step_once_per_example(t *testing.T) {
+	// GIVEN a server that echoes the amount
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"amount": %s}`, r.URL.Query().Get("amount"))
+	})
+	defer server.Close()
+
+	flow := newTestFlow(
+		model.StepSpec{
+			Name:            asPointer("echo"),
+			Get:             asPointer(server.URL + "/echo?amount=${amount}"),
+			Response:        &model.Response{StatusCode: 200, Body: &model.Json{"amount": "${expected}"}},
+			ContinueOnError: true,
+			Examples: &model.Examples{Rows: []map[string]any{
+				{"amount": 1, "expected": 1},
+				{"amount": 2, "expected": 3},
+				{"amount": 3, "expected": 3},
+			}},
+		},
+		model.StepSpec{
+			Name:     asPointer("echo flow amount"),
+			Get:      asPointer(server.URL + "/echo?amount=${amount}"),
+			Response: &model.Response{StatusCode: 200, Body: &model.Json{"amount": 10}},
+		},
+	)
+	flow.Spec.Values = map[string]any{"amount": 10}
+	runner := NewTestRunner(flow, makeLogger())
+
+	// WHEN the flow runs
+	result, err := runner.Run()
+
+	// THEN there is a step result for each example, and the failed ones are reported
+	assert.EqualError(t, err, "steps failed: echo")
+	assert.Len(t, result.Steps, 4)
+	assert.Equal(t, "echo [example 1]", result.Steps[0].Name)
+	assert.Equal(t, results.Passed, result.Steps[0].Status)
+	assert.Equal(t, "echo [example 2]", result.Steps[1].Name)
+	assert.Equal(t, results.Failed, result.Steps[1].Status)
+	assert.Equal(t, results.Passed, result.Steps[2].Status)
+
+	// AND the values of the examples are not visible in the next steps
+	assert.Equal(t, results.Passed, result.Steps[3].Status)
+}
+
+func Test_new_test_runners_runs_the_flow_once_per_example(t *testing.T) {
+	// GIVEN a server that records the paths requested
+	server := newRecordingServer(respondOK)
+	defer server.Close()
+
+	flow := newTestFlow(getStep("create", server.URL+"/clients/${name}"))
+	flow.Spec.Examples = &model.Examples{Rows: []map[string]any{{"name": "James"}, {"name": "Naomi"}}}
+
+	// WHEN the runners run
+	testRunners := NewTestRunners(flow, makeLogger())
+
+	// THEN there is a flow result for each example, using the example values
+	assert.Len(t, testRunners, 2)
+	for i, testRunner := range testRunners {
+		result, err := testRunner.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, model.ExampleName("test-flow", i+1), result.Name)
+		assert.Equal(t, results.Passed, result.Status)
+	}
+	assert.Equal(t, []string{"/clients/James", "/clients/Naomi"}, server.Paths())
+}
+
+func Test_run_evaluates_step_values_in_step_scope(t *testing.T) {
+	// GIVEN a server that returns an id, and records the paths requested
+	server := newRecordingServer(respondJSON(`{"id": 42}`))
+	defer server.Close()
+
+	local := getStep("local", server.URL+"${path}")
+	local.Values = map[string]any{"path": "/clients/${id}"}
+	promoted := getStep("promoted", server.URL+"/accounts/${path}")
+	promoted.Values = map[string]any{"account": "${id}-A"}
+	promoted.PromoteValues = true
+
+	flow := newTestFlow(
+		model.StepSpec{
+			Name:     asPointer("create"),
+			Post:     asPointer(server.URL + "/clients"),
+			Response: &model.Response{StatusCode: 200, Body: &model.Json{"id": "$(id)"}},
+		},
+		local,
+		promoted,
+		getStep("after", server.URL+"/accounts/${account}/${path}"),
+	)
+	runner := NewTestRunner(flow, makeLogger())
+
+	// WHEN the flow runs
+	_, err := runner.Run()
+
+	// THEN step values use extracted values, local values are not visible in the next steps,
+	// and promoted values are
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/clients", "/clients/42", "/accounts/<null>", "/accounts/42-A/<null>"}, server.Paths())
+}
+
+func Test_run_evaluates_step_values_in_dependency_order(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   map[string]any
+		wantPath string
+		wantErr  string
+	}{
+		{name: "value using a later value", values: map[string]any{"path": "${a}", "a": "/a/${c}", "c": "c"}, wantPath: "/a/c"},
+		{name: "value using itself", values: map[string]any{"path": "${path}/b"}, wantPath: "/flow/b"},
+		{name: "cycle", values: map[string]any{"path": "${a}", "a": "${path}"}, wantErr: "values have a cycle: a -> path -> a"},
+	}
+	// GIVEN a server that records the paths requested
+	server := newRecordingServer(respondOK)
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.Reset()
+			step := getStep("get", server.URL+"${path}")
+			step.Values = tt.values
+			flow := newTestFlow(step)
+			flow.Spec.Values = map[string]any{"path": "/flow"}
+
+			// WHEN the flow runs
+			_, err := NewTestRunner(flow, makeLogger(), WithOutput(io.Discard)).Run()
+
+			// THEN the values use the values they depend on, whatever their order
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.wantPath}, server.Paths())
+		})
+	}
+}
+
+func Test_run_reference_with_arguments_and_outputs(t *testing.T) {
+	// GIVEN a server that creates clients for a partner, and records the paths requested
+	server := newRecordingServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"id": "%s-client"}`, strings.TrimPrefix(r.URL.Path, "/partners/"))
+	})
+	defer server.Close()
+
+	// AND a global step that creates a client for the partner in the arguments
+	globalStep := &model.Step{Spec: model.StepSpec{
+		Name:     asPointer("partner-creates-client"),
+		Post:     asPointer(server.URL + "/partners/${partner}"),
+		Response: &model.Response{StatusCode: 200, Body: &model.Json{"id": "$(clientId)"}},
+	}}
+
+	flow := newTestFlow(
+		model.StepSpec{
+			Name:    asPointer("partner-creates-client"),
+			With:    map[string]any{"partner": "${partnerA}"},
+			Outputs: map[string]string{"clientA": "clientId"},
+		},
+		model.StepSpec{
+			Name:    asPointer("partner-creates-client"),
+			With:    map[string]any{"partner": "B"},
+			Outputs: map[string]string{"clientB": "clientId"},
+		},
+		getStep("check", server.URL+"/clients/${clientA}/${clientB}/${clientId}/${partner}"),
+	)
+	flow.Spec.Values = map[string]any{"partnerA": "A"}
+	flow = inCollection(flow, map[string]*model.Step{"partner-creates-client": globalStep})
+
+	// WHEN the flow runs
+	_, err := NewTestRunner(flow, makeLogger()).Run()
+
+	// THEN each reference uses its arguments, and only the outputs are visible in the flow
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/partners/A",
+		"/partners/B",
+		"/clients/A-client/B-client/<null>/<null>",
+	}, server.Paths())
+}
+
+func Test_run_fails_when_output_is_not_set(t *testing.T) {
+	// GIVEN a global step that does not set the value of the output
+	server := newServer(respondOK)
+	defer server.Close()
+
+	globalStep := &model.Step{Spec: getStep("ping", server.URL)}
+	flow := newTestFlow(model.StepSpec{
+		Name:    asPointer("ping"),
+		Outputs: map[string]string{"pong": "missing"},
+	})
+	// AND a value of the flow with the name of the output value
+	flow.Spec.Values = map[string]any{"missing": "flow value"}
+	flow = inCollection(flow, map[string]*model.Step{"ping": globalStep})
+
+	// WHEN the flow runs
+	_, err := NewTestRunner(flow, makeLogger()).Run()
+
+	// THEN the step fails, the value of the flow is not an output of the step
+	assert.EqualError(t, err, "output 'pong' not found, expected value 'missing'")
+}
+
+func Test_run_flow_step(t *testing.T) {
+	// GIVEN a server that returns a token, and records the paths requested
+	server := newRecordingServer(respondJSON(`{"token": "abc"}`))
+	defer server.Close()
+
+	// AND a login flow that extracts the token of the user
+	login := newNamedFlow("login", model.StepSpec{
+		Name:     asPointer("get-token"),
+		Post:     asPointer(server.URL + "/login/${user}"),
+		Response: &model.Response{StatusCode: 200, Body: &model.Json{"token": "$(token)"}},
+	})
+
+	tests := []struct {
+		name    string
+		context string
+		outputs map[string]string
+		want    string
+	}{
+		{"shared context", model.ContextShared, nil, "/accounts/abc/<null>"},
+		{"isolated context", model.ContextIsolated, nil, "/accounts/<null>/<null>"},
+		{"isolated context with outputs", model.ContextIsolated, map[string]string{"token": "token"}, "/accounts/abc/<null>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.Reset()
+			flow := inCollection(newTestFlow(
+				model.StepSpec{
+					Flow:    asPointer("login"),
+					Context: tt.context,
+					With:    map[string]any{"user": "admin"},
+					Outputs: tt.outputs,
+				},
+				getStep("get", server.URL+"/accounts/${token}/${user}"),
+			), nil, login)
+
+			// WHEN the flow runs
+			result, err := NewTestRunner(flow, makeLogger()).Run()
+
+			// THEN the login flow runs with its arguments, and its steps are nested in the step result
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"/login/admin", tt.want}, server.Paths())
+			assert.Len(t, result.Steps, 2)
+			assert.Equal(t, "login", result.Steps[0].Name)
+			assert.Len(t, result.Steps[0].Steps, 1)
+			assert.Equal(t, "get-token", result.Steps[0].Steps[0].Name)
+			assert.Equal(t, results.Passed, result.Steps[0].Steps[0].Status)
+		})
+	}
+}
+
+func Test_run_flow_step_keeps_the_values_of_the_calling_flow(t *testing.T) {
+	// GIVEN a server that records the paths requested
+	server := newRecordingServer(respondOK)
+	defer server.Close()
+
+	// AND a login flow with default values
+	login := newNamedFlow("login", getStep("login", server.URL+"/login/${user}/${tenant}/${realm}"))
+	login.Spec.Values = map[string]any{"user": "guest", "tenant": "default", "realm": "public"}
+
+	for _, stepContext := range []string{model.ContextShared, model.ContextIsolated} {
+		t.Run(stepContext, func(t *testing.T) {
+			server.Reset()
+			flow := newTestFlow(
+				model.StepSpec{Flow: asPointer("login"), Context: stepContext, With: map[string]any{"tenant": "acme"}},
+				getStep("get", server.URL+"/accounts/${user}"),
+			)
+			flow.Spec.Values = map[string]any{"user": "admin"}
+			flow = inCollection(flow, nil, login)
+
+			// WHEN the flow runs
+			_, err := NewTestRunner(flow, makeLogger()).Run()
+
+			// THEN the login flow uses the values of the calling flow and the arguments, and its own values
+			// for the rest, and the values of the calling flow are not changed
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"/login/admin/acme/public", "/accounts/admin"}, server.Paths())
+		})
+	}
+}
+
+func Test_run_flow_step_once_per_example(t *testing.T) {
+	// GIVEN a server that records the paths requested, and fails the requests of the user "fail"
+	server := newRecordingServer(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	defer server.Close()
+
+	// AND a login flow with an example per user
+	login := newNamedFlow("login", getStep("login", server.URL+"/login/${user}"))
+	login.Spec.Examples = &model.Examples{Rows: []map[string]any{{"user": "admin"}, {"user": "fail"}, {"user": "guest"}}}
+
+	flow := inCollection(newTestFlow(
+		model.StepSpec{Flow: asPointer("login"), ContinueOnError: true},
+		getStep("get", server.URL+"/accounts/${user}"),
+	), nil, login)
+
+	// WHEN the flow runs
+	result, err := NewTestRunner(flow, makeLogger()).Run()
+
+	// THEN the login flow runs once per example, even after a failed one, and the values of the examples
+	// are not visible in the calling flow
+	assert.EqualError(t, err, "steps failed: login")
+	assert.Equal(t, []string{"/login/admin", "/login/fail", "/login/guest", "/accounts/<null>"}, server.Paths())
+
+	// AND the results of each example are nested in the step result
+	assert.Equal(t, "flow 'login' failed: examples failed: login [example 2]", result.Steps[0].Error)
+	assert.Len(t, result.Steps[0].Steps, 3)
+	for i, status := range []results.Status{results.Passed, results.Failed, results.Passed} {
+		example := result.Steps[0].Steps[i]
+		assert.Equal(t, model.ExampleName("login", i+1), example.Name)
+		assert.Equal(t, status, example.Status)
+		assert.Len(t, example.Steps, 1)
+	}
+}
+
+func Test_run_teardown_after_failures(t *testing.T) {
+	// GIVEN a server that fails the requests to /fail, and records the paths requested
+	server := newRecordingServer(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		respondJSON(`{"id": 42}`)(w, r)
+	})
+	defer server.Close()
+
+	step := func(name, path string, body any) model.StepSpec {
+		step := getStep(name, server.URL+path)
+		step.Response.Body = body
+		return step
+	}
+
+	tests := []struct {
+		name      string
+		setup     []model.StepSpec
+		wantErr   string
+		wantPaths []string
+	}{
+		{
+			name:      "steps fail",
+			setup:     []model.StepSpec{step("create", "/create", &model.Json{"id": "$(id)"})},
+			wantErr:   "[fail] expected status 200, received 500. Msg: ",
+			wantPaths: []string{"/create", "/fail", "/delete/42", "/fail/teardown", "/delete-all"},
+		},
+		{
+			name:      "setup fails",
+			setup:     []model.StepSpec{step("fail", "/fail/setup", nil)},
+			wantErr:   "setup failed: [fail] expected status 200, received 500. Msg: ",
+			wantPaths: []string{"/fail/setup", "/delete/<null>", "/fail/teardown", "/delete-all"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.Reset()
+			flow := newTestFlow(step("fail", "/fail", nil), step("never", "/never", nil))
+			flow.Spec.Setup = tt.setup
+			flow.Spec.Teardown = []model.StepSpec{
+				step("delete", "/delete/${id}", nil),
+				step("fail-teardown", "/fail/teardown", nil),
+				step("delete-all", "/delete-all", nil),
+			}
+
+			// WHEN the flow runs
+			result, err := NewTestRunner(flow, makeLogger()).Run()
+
+			// THEN all the teardown steps run, with the values extracted before the failure
+			assert.EqualError(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantPaths, server.Paths())
+			assert.Len(t, result.Steps, 6)
+			assert.Equal(t, results.Setup, result.Steps[0].Phase)
+			assert.Equal(t, results.Skipped, result.Steps[2].Status)
+			assert.Equal(t, results.Teardown, result.Steps[5].Phase)
+			assert.Equal(t, results.Passed, result.Steps[5].Status)
+		})
+	}
+}
+
+func Test_run_teardown_when_interrupted(t *testing.T) {
+	// GIVEN a server that records the paths requested
+	server := newRecordingServer(respondOK)
+	defer server.Close()
+
+	flow := newTestFlow(getStep("create", server.URL+"/create"))
+	flow.Spec.Teardown = []model.StepSpec{{
+		Name:     asPointer("delete"),
+		Delete:   asPointer(server.URL + "/delete"),
+		Response: &model.Response{StatusCode: 200},
+	}}
+
+	// AND the flow is already interrupted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// WHEN the flow runs
+	result, err := NewTestRunner(flow, makeLogger(), WithContext(ctx)).Run()
+
+	// THEN the steps are skipped, but the teardown runs
+	assert.EqualError(t, err, "interrupted")
+	assert.Equal(t, []string{"/delete"}, server.Paths())
+	assert.Equal(t, results.Skipped, result.Steps[0].Status)
+	assert.Equal(t, results.Passed, result.Steps[1].Status)
+}
+
+func Test_run_polls_until_response_matches(t *testing.T) {
+	// GIVEN a server that approves the credit in the third request
+	requests := 0
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		status := "pending"
+		if requests >= 3 {
+			status = "approved"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status": "%s"}`, status)
+	})
+	defer server.Close()
+
+	approved := &model.Json{"status": "approved"}
+
+	tests := []struct {
+		name            string
+		poll            *model.Poll
+		body            *model.Json
+		wantErr         string
+		wantAttempts    int
+		wantDifferences int
+	}{
+		{
+			name:         "matches before timeout",
+			poll:         &model.Poll{Interval: 10 * time.Millisecond, Timeout: time.Second, Backoff: 2},
+			body:         approved,
+			wantAttempts: 3,
+		},
+		{
+			name:            "times out",
+			poll:            &model.Poll{Interval: 50 * time.Millisecond, Timeout: 80 * time.Millisecond},
+			body:            approved,
+			wantErr:         "poll timed out after 2 attempts: status: different.\n  Expected: approved\n  Actual: pending\n",
+			wantAttempts:    2,
+			wantDifferences: 1,
+		},
+		{
+			name:         "fails fast when it is not a mismatch",
+			poll:         &model.Poll{Interval: 10 * time.Millisecond, Timeout: time.Second},
+			body:         &model.Json{"status": "$(status:unknown)"},
+			wantErr:      "invalid expected value: status: invalid expression: $(status:unknown) at 9: unknown matcher type: unknown",
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			flow := newTestFlow(model.StepSpec{
+				Name:     asPointer("wait-approval"),
+				Get:      asPointer(server.URL + "/credits/1"),
+				Poll:     tt.poll,
+				Response: &model.Response{StatusCode: 200, Body: tt.body},
+			})
+
+			// WHEN the flow runs
+			result, err := NewTestRunner(flow, makeLogger()).Run()
+
+			// THEN the request is repeated while the response does not match, and the last differences are reported
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+			assert.Len(t, result.Steps[0].Differences, tt.wantDifferences)
+			assert.Len(t, result.Steps[0].Attempts, tt.wantAttempts)
+		})
+	}
+}
+
+func Test_run_retries_temporary_failures(t *testing.T) {
+	// GIVEN a server that is unavailable in the first two requests
+	requests := 0
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		step         model.StepSpec
+		wantErr      bool
+		wantAttempts int
+	}{
+		{
+			name:         "idempotent methods are retried",
+			step:         model.StepSpec{Get: asPointer(server.URL)},
+			wantAttempts: 3,
+		},
+		{
+			name:         "other methods are not retried",
+			step:         model.StepSpec{Post: asPointer(server.URL)},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "other methods are retried if configured",
+			step:         model.StepSpec{Post: asPointer(server.URL), Retry: model.Retry{Methods: []string{"POST"}}},
+			wantAttempts: 3,
+		},
+		{
+			name:         "up to the maximum attempts",
+			step:         model.StepSpec{Get: asPointer(server.URL), Retry: model.Retry{Attempts: 2}},
+			wantErr:      true,
+			wantAttempts: 2,
+		},
+		{
+			name:         "only the given status codes",
+			step:         model.StepSpec{Get: asPointer(server.URL), Retry: model.Retry{StatusCodes: []int{502}}},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			tt.step.Response = &model.Response{StatusCode: 200}
+			flow := newTestFlow(tt.step)
+			flow.Spec.Retry = model.Retry{Interval: time.Millisecond}
+
+			// WHEN the flow runs
+			result, err := NewTestRunner(flow, makeLogger()).Run()
+
+			// THEN the request is sent again, and each request is recorded
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Len(t, result.Steps[0].Attempts, tt.wantAttempts)
+			assert.Equal(t, 503, result.Steps[0].Attempts[0].StatusCode)
+		})
+	}
+}
+
+func Test_run_retries_connection_errors(t *testing.T) {
+	// GIVEN a server that is not running
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {})
+	server.Close()
+
+	flow := newTestFlow(model.StepSpec{Get: asPointer(server.URL), Response: &model.Response{StatusCode: 200}})
+	flow.Spec.Retry = model.Retry{Interval: time.Millisecond}
+
+	// WHEN the flow runs
+	result, err := NewTestRunner(flow, makeLogger()).Run()
+
+	// THEN the request is sent up to the default attempts
+	assert.Error(t, err)
+	assert.Len(t, result.Steps[0].Attempts, 3)
+	assert.Contains(t, result.Steps[0].Attempts[0].Error, "connection refused")
+}
+
+func Test_run_limits_the_time_of_requests_and_flows(t *testing.T) {
+	// GIVEN a server that takes 100ms to respond the slow requests
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	noRetry := model.Retry{Attempts: 1}
+
+	tests := []struct {
+		name         string
+		flowTimeout  time.Duration
+		step         model.StepSpec
+		wantErr      string
+		wantStatuses []results.Status
+	}{
+		{
+			name:         "request in time",
+			step:         model.StepSpec{Get: asPointer(server.URL + "/slow"), Timeout: time.Second},
+			wantStatuses: []results.Status{results.Passed, results.Passed},
+		},
+		{
+			name:         "request timed out",
+			step:         model.StepSpec{Get: asPointer(server.URL + "/slow"), Timeout: 20 * time.Millisecond, Retry: noRetry},
+			wantErr:      "request timed out after 20ms",
+			wantStatuses: []results.Status{results.Failed, results.Passed},
+		},
+		{
+			name:         "response slower than the max duration",
+			step:         model.StepSpec{Get: asPointer(server.URL + "/slow"), Response: &model.Response{StatusCode: 200, MaxDuration: 20 * time.Millisecond}},
+			wantErr:      "[slow] expected response in 20ms, received in",
+			wantStatuses: []results.Status{results.Failed, results.Passed},
+		},
+		{
+			name:         "flow timed out",
+			flowTimeout:  20 * time.Millisecond,
+			step:         model.StepSpec{Get: asPointer(server.URL + "/slow"), Retry: noRetry},
+			wantErr:      "flow timed out",
+			wantStatuses: []results.Status{results.Failed, results.Passed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.step.Name = asPointer("slow")
+			if tt.step.Response == nil {
+				tt.step.Response = &model.Response{StatusCode: 200}
+			}
+			flow := newTestFlow(tt.step)
+			flow.Spec.Timeout = tt.flowTimeout
+			flow.Spec.Teardown = []model.StepSpec{{Get: asPointer(server.URL + "/fast"), Response: &model.Response{StatusCode: 200}}}
+
+			// WHEN the flow runs
+			result, err := NewTestRunner(flow, makeLogger()).Run()
+
+			// THEN the slow step fails, and the teardown runs anyway
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+			var statuses []results.Status
+			for _, step := range result.Steps {
+				statuses = append(statuses, step.Status)
+			}
+			assert.Equal(t, tt.wantStatuses, statuses)
+			assert.NotZero(t, result.Steps[0].Duration)
+		})
+	}
+}
+
+func Test_run_sends_query_form_and_multipart(t *testing.T) {
+	// GIVEN a server that responds with the parameters received
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		received := map[string]any{}
+		if r.URL.RawQuery != "" {
+			received["query"] = r.URL.RawQuery
+		}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			_ = r.ParseMultipartForm(1 << 20)
+			file, header, _ := r.FormFile("document")
+			content, _ := io.ReadAll(file)
+			received["form"] = r.MultipartForm.Value
+			received["file"] = map[string]any{"name": header.Filename, "type": header.Header.Get("Content-Type"), "content": string(content)}
+		} else {
+			_ = r.ParseForm()
+			received["form"] = r.PostForm
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(received)
+	})
+	defer server.Close()
+
+	// AND a file to upload
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "contract.txt"), []byte("signed"), 0o600))
+	document := model.Part{Name: "document", File: "${fileName}", ContentType: "text/plain"}
+	document.SetDir(dir)
+
+	tests := []struct {
+		name     string
+		step     model.StepSpec
+		expected model.Json
+	}{
+		{
+			name: "query",
+			step: model.StepSpec{
+				Get:   asPointer(server.URL),
+				Query: map[string]any{"name": "${name}", "page": 2, "tag": []any{"a", "b"}},
+			},
+			expected: model.Json{"query": "name=Ana+%26+Bob&page=2&tag=a&tag=b", "form": map[string]any{}},
+		},
+		{
+			name: "form",
+			step: model.StepSpec{
+				Post: asPointer(server.URL),
+				Form: map[string]any{"name": "${name}", "accepted": true},
+			},
+			expected: model.Json{"form": map[string]any{"name": []any{"Ana & Bob"}, "accepted": []any{"true"}}},
+		},
+		{
+			name: "multipart",
+			step: model.StepSpec{
+				Post:      asPointer(server.URL),
+				Multipart: []model.Part{{Name: "owner", Value: "${name}"}, document},
+			},
+			expected: model.Json{
+				"form": map[string]any{"owner": []any{"Ana & Bob"}},
+				"file": map[string]any{"name": "contract.txt", "type": "text/plain", "content": "signed"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.step.Response = &model.Response{StatusCode: 200, Body: &tt.expected}
+			flow := newTestFlow(tt.step)
+			flow.Spec.Values = map[string]any{"name": "Ana & Bob", "fileName": "contract.txt"}
+
+			// WHEN the flow runs
+			result, err := NewTestRunner(flow, makeLogger(), WithOutput(io.Discard)).Run()
+
+			// THEN the parameters are encoded in the request
+			assert.NoError(t, err)
+			assert.Empty(t, result.Steps[0].Differences)
+		})
+	}
+}
+
+func Test_run_checks_bodies_of_any_format(t *testing.T) {
+	// GIVEN a server that responds bodies of several formats
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/array":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"id": 1}, {"id": 2}]`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("Credit 42 approved"))
+		case "/xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<credit id="42"><status>approved</status></credit>`))
+		case "/binary":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.4"))
+		case "/empty":
+			w.WriteHeader(http.StatusOK)
+		case "/invalid":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("<html>Credit 42</html>"))
+		case "/error":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("<html>Internal Server Error</html>"))
+		}
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		format   string
+		expected any
+		wantErr  string
+	}{
+		{name: "json array", path: "/array", expected: []any{map[string]any{"id": 1}, map[string]any{"id": "$(secondId)"}}},
+		{name: "exact text", path: "/text", expected: "Credit 42 approved"},
+		{name: "text matching a regex", path: "/text", expected: "$(:/Credit \\d+ approved/)"},
+		{name: "different text", path: "/text", expected: "Credit 42 rejected", wantErr: "different"},
+		{name: "xml", path: "/xml", expected: map[string]any{"credit": map[string]any{"@id": "42", "status": "approved"}}},
+		{name: "binary size", path: "/binary", expected: map[string]any{"size": 8}},
+		{name: "text as binary", path: "/text", format: model.FormatBinary, expected: map[string]any{"size": 18}},
+		{name: "empty", path: "/empty", format: model.FormatNone},
+		{name: "not empty", path: "/text", format: model.FormatNone, wantErr: "expected an empty body, received 18 bytes"},
+		{name: "invalid body not expected", path: "/invalid"},
+		{name: "invalid body", path: "/invalid", expected: map[string]any{"id": 42}, wantErr: "invalid character '<'"},
+		{name: "status before invalid body", path: "/error", expected: map[string]any{"id": 42}, wantErr: "expected status 200, received 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow := newTestFlow(model.StepSpec{
+				Get:      asPointer(server.URL + tt.path),
+				Response: &model.Response{StatusCode: 200, Body: tt.expected, Format: tt.format},
+			})
+			runner := NewTestRunner(flow, makeLogger(), WithOutput(io.Discard))
+
+			// WHEN the flow runs
+			_, err := runner.Run()
+
+			// THEN the body is decoded by its format and compared, only if a body is expected, after the status
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_run_sends_any_method(t *testing.T) {
+	// GIVEN a server that records the methods received
+	var methods []string
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS, PROPFIND")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	response := &model.Response{StatusCode: 204}
+	flow := newTestFlow(
+		model.StepSpec{Head: asPointer(server.URL), Response: response},
+		model.StepSpec{Options: asPointer(server.URL), Response: &model.Response{StatusCode: 204, Headers: model.Headers{"Allow": "$(:/.*PROPFIND.*/)"}}},
+		model.StepSpec{Trace: asPointer(server.URL), Response: response},
+		model.StepSpec{CustomMethod: asPointer("propfind"), URL: asPointer(server.URL), Response: response},
+	)
+
+	// WHEN the flow runs
+	_, err := NewTestRunner(flow, makeLogger(), WithOutput(io.Discard)).Run()
+
+	// THEN each request is sent with its method
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"HEAD", "OPTIONS", "TRACE", "PROPFIND"}, methods)
+}
+
+func Test_run_renews_oauth2_tokens_rejected(t *testing.T) {
+	// GIVEN a token endpoint that issues a new token in each request
+	tokens := 0
+	tokenServer := newServer(func(w http.ResponseWriter, r *http.Request) {
+		tokens++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, tokens)
+	})
+	defer tokenServer.Close()
+
+	// AND a server that rejects the first token, as if it was revoked
+	var authorizations []string
+	server := newServer(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"echo": "%s"}`, r.URL.Query().Get("token"))
+	})
+	defer server.Close()
+
+	flow := newTestFlow(
+		model.StepSpec{Name: asPointer("get"), Get: asPointer(server.URL), Response: &model.Response{StatusCode: 200}},
+		model.StepSpec{
+			Name:     asPointer("token in expressions"),
+			Get:      asPointer(server.URL),
+			Query:    map[string]any{"token": "${accessToken}"},
+			Response: &model.Response{StatusCode: 200, Body: model.Json{"echo": "token-2"}},
+		},
+	)
+	flow.Spec.Auth = &model.Auth{OAuth2: &model.OAuth2Auth{TokenURL: tokenServer.URL, ClientID: "partner"}}
+
+	// WHEN the flow runs
+	result, err := NewTestRunner(flow, makeLogger(), WithOutput(io.Discard)).Run()
+
+	// THEN the rejected request is sent again with a new token, and the token is reused
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2", "Bearer token-2"}, authorizations)
+	assert.Len(t, result.Steps[0].Attempts, 2)
+	assert.Equal(t, 2, tokens)
+}
@@ -0,0 +1,80 @@
+package runners
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/totemcaf/test-by-example.git/internal/model"
+)
+
+// configureTLS configures the TLS connections of the client with the global TLS and the TLS of the flows
+func (r *testRunner) configureTLS() error {
+	config, err := tlsConfig(append([]*model.TLS{r.tls}, r.flowTLS...)...)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	if config != nil {
+		r.client.SetTLSClientConfig(config)
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the specs, or nil if none is set. The CAs of all the specs are
+// trusted, and the client certificates and server name of a spec replace the ones of the previous specs
+func tlsConfig(specs ...*model.TLS) (*tls.Config, error) {
+	var config *tls.Config
+
+	for _, spec := range specs {
+		if spec == nil {
+			continue
+		}
+		if config == nil {
+			config = &tls.Config{}
+		}
+
+		for _, file := range spec.CACerts {
+			if config.RootCAs == nil {
+				config.RootCAs = systemCertPool()
+			}
+
+			path := spec.Path(file)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if !config.RootCAs.AppendCertsFromPEM(content) {
+				return nil, fmt.Errorf("no certificates found in %s", path)
+			}
+		}
+
+		if len(spec.ClientCerts) > 0 {
+			config.Certificates = nil
+		}
+		for _, clientCert := range spec.ClientCerts {
+			cert, err := tls.LoadX509KeyPair(spec.Path(clientCert.Cert), spec.Path(clientCert.Key))
+			if err != nil {
+				return nil, err
+			}
+			config.Certificates = append(config.Certificates, cert)
+		}
+
+		if spec.ServerName != "" {
+			config.ServerName = spec.ServerName
+		}
+		config.InsecureSkipVerify = config.InsecureSkipVerify || spec.InsecureSkipVerify
+	}
+
+	return config, nil
+}
+
+// systemCertPool returns a copy of the CAs of the system, or an empty pool if they cannot be read
+func systemCertPool() *x509.CertPool {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return x509.NewCertPool()
+	}
+	return pool
+}
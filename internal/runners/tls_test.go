@@ -0,0 +1,178 @@
+package runners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/totemcaf/test-by-example.git/internal/model"
+	"github.com/totemcaf/test-by-example.git/internal/results"
+)
+
+func writePEM(t *testing.T, fileName string, blockType string, content []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: content})
+	assert.NoError(t, os.WriteFile(fileName, data, 0600))
+}
+
+// writeClientCert writes a self-signed client certificate and its key in the folder
+func writeClientCert(t *testing.T, dir string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "partner"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	writePEM(t, filepath.Join(dir, "client.pem"), "CERTIFICATE", cert)
+	writePEM(t, filepath.Join(dir, "client-key.pem"), "EC PRIVATE KEY", keyBytes)
+}
+
+func Test_run_configures_tls(t *testing.T) {
+	// GIVEN a TLS server that requests a client certificate, and records the common name received
+	var clients []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, cert := range r.TLS.PeerCertificates {
+			clients = append(clients, cert.Subject.CommonName)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// AND the certificate of its CA, and a client certificate, in the folder of the spec
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", server.Certificate().Raw)
+	writeClientCert(t, dir)
+	t.Setenv("CERTS_DIR", dir)
+
+	tests := []struct {
+		name        string
+		global      *model.TLS
+		flow        *model.TLS
+		wantError   string
+		wantClients []string
+	}{
+		{name: "without tls", wantError: "certificate"},
+		{name: "ca relative to the spec", flow: &model.TLS{CACerts: []string{"ca.pem"}}},
+		{name: "ca with environment variables", global: &model.TLS{CACerts: []string{"${CERTS_DIR}/ca.pem"}}},
+		{name: "server name", flow: &model.TLS{CACerts: []string{"ca.pem"}, ServerName: "example.com"}},
+		{name: "invalid server name", flow: &model.TLS{CACerts: []string{"ca.pem"}, ServerName: "example.org"}, wantError: "example.org"},
+		{name: "insecure", global: &model.TLS{InsecureSkipVerify: true}},
+		{
+			name:        "client certificate",
+			global:      &model.TLS{CACerts: []string{"${CERTS_DIR}/ca.pem"}},
+			flow:        &model.TLS{ClientCerts: []model.ClientCert{{Cert: "client.pem", Key: "client-key.pem"}}},
+			wantClients: []string{"partner"},
+		},
+		{name: "missing ca", flow: &model.TLS{CACerts: []string{"missing.pem"}}, wantError: "tls: open"},
+		{name: "ca without certificates", flow: &model.TLS{CACerts: []string{"client-key.pem"}}, wantError: "no certificates found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients = nil
+			flow := newTestFlow(model.StepSpec{Get: asPointer(server.URL), Response: &model.Response{StatusCode: 204}})
+			flow.Spec.TLS = tt.flow
+			flow.Spec.TLS.SetDir(dir)
+
+			// WHEN the flow runs
+			_, err := NewTestRunner(flow, makeLogger(), WithOutput(io.Discard), WithTLS(tt.global)).Run()
+
+			// THEN the server is trusted, and the client certificate is sent
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantClients, clients)
+		})
+	}
+}
+
+func Test_run_configures_the_tls_of_flows_run_as_steps(t *testing.T) {
+	// GIVEN a TLS server that requests a client certificate, and records the common name received
+	var clients []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, cert := range r.TLS.PeerCertificates {
+			clients = append(clients, cert.Subject.CommonName)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// AND the certificate of its CA, and a client certificate, in the folder of the specs
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", server.Certificate().Raw)
+	writeClientCert(t, dir)
+
+	trustCA := &model.TLS{CACerts: []string{"ca.pem"}}
+	sendCert := &model.TLS{ClientCerts: []model.ClientCert{{Cert: "client.pem", Key: "client-key.pem"}}}
+
+	tests := []struct {
+		name        string
+		flow        *model.TLS
+		subFlow     *model.TLS
+		after       bool
+		wantError   string
+		wantClients []string
+	}{
+		{name: "tls of the flow step", subFlow: trustCA},
+		{name: "tls added to the one of the running flow", flow: trustCA, subFlow: sendCert, wantClients: []string{"partner"}},
+		{name: "running flow keeps its tls", subFlow: trustCA, after: true, wantError: "certificate signed by unknown authority"},
+		{name: "invalid tls of the flow step", subFlow: &model.TLS{CACerts: []string{"missing.pem"}}, wantError: "flow 'secure': tls: open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients = nil
+			subFlow := newNamedFlow("secure", getStep("get", server.URL))
+			subFlow.Spec.TLS = tt.subFlow
+			subFlow.Spec.TLS.SetDir(dir)
+
+			steps := []model.StepSpec{{Name: asPointer("secure"), Flow: asPointer("secure")}}
+			if tt.after {
+				steps = append(steps, getStep("after", server.URL))
+			}
+			flow := newNamedFlow("main", steps...)
+			flow.Spec.TLS = tt.flow
+			flow.Spec.TLS.SetDir(dir)
+
+			// WHEN the flow runs
+			result, err := NewTestRunner(inCollection(flow, nil, subFlow), makeLogger(), WithOutput(io.Discard)).Run()
+
+			// THEN the requests of the flow step use its TLS on top of the one of the running flow, and the
+			// running flow keeps its own
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				if tt.after {
+					assert.Equal(t, results.Passed, result.Steps[0].Status)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantClients, clients)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package jsonx
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 )
 
@@ -18,7 +19,7 @@ func (n *arrayType) MarshalJSON() ([]byte, error) {
 }
 
 func (n *arrayType) String() string {
-	panic("implement array.String")
+	return fmt.Sprintf("%v", n.values)
 }
 
 func (n *arrayType) Equals(_ JsonX) bool {
@@ -34,19 +35,27 @@ func (n *arrayType) Diff(context Context, actual JsonX) Differences {
 		return Differences{{nil, n, n, actual, "expected array"}}
 	}
 
-	if len(n.values) != len(actualArray.values) {
-		return Differences{{nil, n, n, actual, "different array lengths"}}
-	}
-
 	actualValues := actualArray.values
 
+	if len(n.values) != len(actualValues) {
+		diffs = append(diffs, &Difference{nil, n, n, actual, fmt.Sprintf("different array lengths, expected %d, found %d", len(n.values), len(actualValues))})
+	}
+
 	for i, value := range n.values {
+		if i >= len(actualValues) {
+			diffs = append(diffs, &Difference{[]string{strconv.Itoa(i)}, value, value, NullX, "missing value"})
+			continue
+		}
 		diff := value.Diff(context, actualValues[i])
 		if len(diff) > 0 {
 			diffs = append(diffs, diff.addPath(strconv.Itoa(i))...)
 		}
 	}
 
+	for i := len(n.values); i < len(actualValues); i++ {
+		diffs = append(diffs, &Difference{[]string{strconv.Itoa(i)}, NullX, NullX, actualValues[i], "extra value"})
+	}
+
 	return diffs
 }
 
@@ -0,0 +1,319 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	ArrayMatcher Type = "arrayMatcher"
+)
+
+// Array directives are map keys that replace the map by an array matcher
+const (
+	unorderedDirective = "$unordered"
+	containsDirective  = "$contains"
+	eachDirective      = "$each"
+	lengthDirective    = "$length"
+	minLengthDirective = "$minLength"
+	maxLengthDirective = "$maxLength"
+)
+
+var arrayDirectives = map[string]bool{
+	unorderedDirective: true,
+	containsDirective:  true,
+	eachDirective:      true,
+	lengthDirective:    true,
+	minLengthDirective: true,
+	maxLengthDirective: true,
+}
+
+type arrayMatch string
+
+const (
+	// matchUnordered expects the same elements in any order
+	matchUnordered arrayMatch = unorderedDirective
+	// matchContains expects the elements in any order, other elements are allowed
+	matchContains arrayMatch = containsDirective
+	// matchEach expects all the elements to match the template
+	matchEach arrayMatch = eachDirective
+	// matchLength only checks the array length
+	matchLength arrayMatch = lengthDirective
+)
+
+const noMaxLength = -1
+
+// arrayMatcherType compares arrays without requiring the same order or the same length
+type arrayMatcherType struct {
+	match     arrayMatch
+	values    []JsonX
+	each      JsonX
+	minLength int
+	maxLength int
+}
+
+func (n *arrayMatcherType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.values)
+}
+
+func (n *arrayMatcherType) String() string {
+	var parts []string
+
+	switch n.match {
+	case matchUnordered, matchContains:
+		parts = append(parts, fmt.Sprintf("%s: %v", n.match, n.values))
+	case matchEach:
+		parts = append(parts, fmt.Sprintf("%s: %v", n.match, n.each))
+	}
+	if n.minLength > 0 {
+		parts = append(parts, fmt.Sprintf("%s: %d", minLengthDirective, n.minLength))
+	}
+	if n.maxLength != noMaxLength {
+		parts = append(parts, fmt.Sprintf("%s: %d", maxLengthDirective, n.maxLength))
+	}
+
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (n *arrayMatcherType) Equals(_ JsonX) bool {
+	panic("implement arrayMatcher.Equals")
+}
+
+func (n *arrayMatcherType) Diff(context Context, actual JsonX) Differences {
+	actualArray, ok := actual.(*arrayType)
+
+	if !ok {
+		return Differences{{nil, n, n, actual, "expected array"}}
+	}
+
+	diffs := n.diffLength(actualArray)
+
+	switch n.match {
+	case matchUnordered:
+		diffs = append(diffs, n.diffUnordered(context, actualArray, true)...)
+	case matchContains:
+		diffs = append(diffs, n.diffUnordered(context, actualArray, false)...)
+	case matchEach:
+		for i, value := range actualArray.values {
+			diffs = append(diffs, n.each.Diff(context, value).addPath(strconv.Itoa(i))...)
+		}
+	}
+
+	if len(diffs) == 0 {
+		return nil
+	}
+	return diffs
+}
+
+func (n *arrayMatcherType) diffLength(actual *arrayType) Differences {
+	length := len(actual.values)
+
+	if length < n.minLength {
+		return Differences{{nil, n, n, actual, fmt.Sprintf("expected at least %d elements, found %d", n.minLength, length)}}
+	}
+	if n.maxLength != noMaxLength && length > n.maxLength {
+		return Differences{{nil, n, n, actual, fmt.Sprintf("expected at most %d elements, found %d", n.maxLength, length)}}
+	}
+	return nil
+}
+
+// diffUnordered pairs each expected element with a different actual element, and reports the expected
+// elements without pair. If exhaustive, the actual elements without pair are also reported.
+func (n *arrayMatcherType) diffUnordered(context Context, actual *arrayType, exhaustive bool) Differences {
+	matches := n.candidates(context, actual)
+	pairs := pairElements(matches, len(actual.values))
+
+	var diffs Differences
+	paired := make([]bool, len(actual.values))
+
+	for i, value := range n.values {
+		j := pairs[i]
+		if j < 0 {
+			diffs = append(diffs, &Difference{[]string{strconv.Itoa(i)}, value, value, actual, "no matching element"})
+			continue
+		}
+		paired[j] = true
+		// Compare again with the real context to keep the extracted values
+		_ = value.Diff(context, actual.values[j])
+	}
+
+	if exhaustive {
+		for j, value := range actual.values {
+			if !paired[j] {
+				diffs = append(diffs, &Difference{[]string{strconv.Itoa(j)}, NullX, NullX, value, "extra value"})
+			}
+		}
+	}
+
+	return diffs
+}
+
+// candidates returns, for each expected element, the actual elements it matches. Values are compared
+// in a scratch context, so failed attempts do not change the context
+func (n *arrayMatcherType) candidates(context Context, actual *arrayType) [][]int {
+	options := diffOptionsOf(context)
+	matches := make([][]int, len(n.values))
+
+	for i, expected := range n.values {
+		for j, value := range actual.values {
+			scratch := withDiffOptions(newScratchContext(context), options)
+			if len(expected.Diff(scratch, value)) == 0 {
+				matches[i] = append(matches[i], j)
+			}
+		}
+	}
+
+	return matches
+}
+
+// pairElements finds a maximum matching between expected and actual elements using augmenting paths.
+// It returns the actual element paired with each expected element, or -1 if there is none.
+func pairElements(matches [][]int, actualLen int) []int {
+	expectedFor := make([]int, actualLen)
+	for j := range expectedFor {
+		expectedFor[j] = -1
+	}
+
+	var augment func(i int, visited []bool) bool
+	augment = func(i int, visited []bool) bool {
+		for _, j := range matches[i] {
+			if visited[j] {
+				continue
+			}
+			visited[j] = true
+			if expectedFor[j] < 0 || augment(expectedFor[j], visited) {
+				expectedFor[j] = i
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range matches {
+		augment(i, make([]bool, actualLen))
+	}
+
+	pairs := make([]int, len(matches))
+	for i := range pairs {
+		pairs[i] = -1
+	}
+	for j, i := range expectedFor {
+		if i >= 0 {
+			pairs[i] = j
+		}
+	}
+	return pairs
+}
+
+func (n *arrayMatcherType) Eval(context Context) JsonX {
+	values := make([]JsonX, len(n.values))
+	for i, value := range n.values {
+		values[i] = value.Eval(context)
+	}
+	return &arrayType{values: values}
+}
+
+func (n *arrayMatcherType) Type() Type {
+	return ArrayMatcher
+}
+
+func isArrayMatcher(value reflect.Value) bool {
+	for _, key := range value.MapKeys() {
+		if arrayDirectives[fmt.Sprintf("%v", key.Interface())] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKey returns true if the map has the given key
+func hasKey(value reflect.Value, key string) bool {
+	for _, mapKey := range value.MapKeys() {
+		if fmt.Sprintf("%v", mapKey.Interface()) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseArrayMatcher(value reflect.Value) (JsonX, error) {
+	matcher := &arrayMatcherType{match: matchLength, maxLength: noMaxLength}
+
+	if hasKey(value, lengthDirective) && (hasKey(value, minLengthDirective) || hasKey(value, maxLengthDirective)) {
+		return nil, fmt.Errorf("invalid array directives: %s cannot be used with %s or %s", lengthDirective, minLengthDirective, maxLengthDirective)
+	}
+
+	for _, key := range value.MapKeys() {
+		keyStr := fmt.Sprintf("%v", key.Interface())
+		directiveValue := value.MapIndex(key)
+
+		if match := arrayMatch(keyStr); match == matchUnordered || match == matchContains || match == matchEach {
+			if matcher.match != matchLength {
+				return nil, fmt.Errorf("invalid array directives: %s and %s cannot be used together", matcher.match, match)
+			}
+			matcher.match = match
+		}
+
+		var err error
+
+		switch keyStr {
+		case unorderedDirective, containsDirective:
+			var elements JsonX
+			if elements, err = p.parse(directiveValue); err != nil {
+				return nil, fmt.Errorf("%s: %w", keyStr, err)
+			}
+			array, ok := elements.(*arrayType)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s directive: expected a list of elements", keyStr)
+			}
+			matcher.values = array.values
+		case eachDirective:
+			if matcher.each, err = p.parse(directiveValue); err != nil {
+				return nil, fmt.Errorf("%s: %w", keyStr, err)
+			}
+		case lengthDirective:
+			matcher.minLength, err = parseLengthDirective(keyStr, directiveValue)
+			matcher.maxLength = matcher.minLength
+		case minLengthDirective:
+			matcher.minLength, err = parseLengthDirective(keyStr, directiveValue)
+		case maxLengthDirective:
+			matcher.maxLength, err = parseLengthDirective(keyStr, directiveValue)
+		default:
+			err = fmt.Errorf("invalid array directives: unexpected key %s", keyStr)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return matcher, nil
+}
+
+func parseLengthDirective(name string, value reflect.Value) (int, error) {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Int() >= 0 {
+			return int(value.Int()), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if length := value.Float(); length >= 0 && length == float64(int(length)) {
+			return int(length), nil
+		}
+	case reflect.String:
+		if value.Type() == jsonNumberType {
+			if length, err := strconv.Atoi(value.String()); err == nil && length >= 0 {
+				return length, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("invalid %s directive: expected a non negative integer", name)
+}
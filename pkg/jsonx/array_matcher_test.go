@@ -0,0 +1,155 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_array_matcher_diff(t *testing.T) {
+	unordered := p(map[string]any{"$unordered": []any{"hello", "world"}})
+	contains := p(map[string]any{"$contains": []any{"world"}})
+	each := p(map[string]any{"$each": map[string]any{"id": "$(:int)"}})
+	bounded := p(map[string]any{"$minLength": 1, "$maxLength": 2})
+
+	tests := []struct {
+		name     string
+		expected JsonX
+		actual   JsonX
+		want     Differences
+	}{
+		{"unordered same order", unordered, p([]any{"hello", "world"}), nil},
+		{"unordered other order", unordered, p([]any{"world", "hello"}), nil},
+		{
+			"unordered missing element",
+			unordered,
+			p([]any{"world", "moon"}),
+			Differences{
+				{[]string{"0"}, p("hello"), p("hello"), p([]any{"world", "moon"}), "no matching element"},
+				{[]string{"1"}, p(nil), p(nil), p("moon"), "extra value"},
+			},
+		},
+		{
+			"unordered extra element",
+			unordered,
+			p([]any{"world", "hello", "moon"}),
+			Differences{
+				{[]string{"2"}, p(nil), p(nil), p("moon"), "extra value"},
+			},
+		},
+		{
+			"unordered repeated element",
+			p(map[string]any{"$unordered": []any{"hello", "hello"}}),
+			p([]any{"hello", "world"}),
+			Differences{
+				{[]string{"1"}, p("hello"), p("hello"), p([]any{"hello", "world"}), "no matching element"},
+				{[]string{"1"}, p(nil), p(nil), p("world"), "extra value"},
+			},
+		},
+		{
+			"unordered pairs general and specific elements",
+			p(map[string]any{"$unordered": []any{"$(:string)", "hello"}}),
+			p([]any{"hello", "world"}),
+			nil,
+		},
+		{"contains element", contains, p([]any{"hello", "world", "moon"}), nil},
+		{
+			"contains missing element",
+			contains,
+			p([]any{"hello", "moon"}),
+			Differences{
+				{[]string{"0"}, p("world"), p("world"), p([]any{"hello", "moon"}), "no matching element"},
+			},
+		},
+		{"each element matches", each, p([]any{map[string]any{"id": 1}, map[string]any{"id": 2}}), nil},
+		{"each with empty array", each, p([]any{}), nil},
+		{
+			"each element does not match",
+			each,
+			p([]any{map[string]any{"id": 1}, map[string]any{"id": "2"}}),
+			Differences{
+				{[]string{"id", "1"}, p("$(:int)"), p("$(:int)"), p("2"), "expected int"},
+			},
+		},
+		{"length in bounds", bounded, p([]any{"hello"}), nil},
+		{
+			"length too short",
+			bounded,
+			p([]any{}),
+			Differences{{nil, bounded, bounded, p([]any{}), "expected at least 1 elements, found 0"}},
+		},
+		{
+			"length too long",
+			bounded,
+			p([]any{1, 2, 3}),
+			Differences{{nil, bounded, bounded, p([]any{1, 2, 3}), "expected at most 2 elements, found 3"}},
+		},
+		{
+			"not an array",
+			contains,
+			p("world"),
+			Differences{{nil, contains, contains, p("world"), "expected array"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			differences := tt.expected.Diff(NewContext(), tt.actual)
+			assert.Equalf(t, tt.want, differences, "Diff(context, %v, %v)", tt.expected, tt.actual)
+		})
+	}
+}
+
+func Test_array_matcher_extracts_values_only_from_paired_elements(t *testing.T) {
+	// GIVEN an expected element with an extractor that matches only one actual element
+	context := NewContext()
+	expected := p(map[string]any{"$contains": []any{map[string]any{"id": "$(id)", "name": "Chrisjen"}}})
+
+	// WHEN compute diff
+	differences := expected.Diff(context, p([]any{
+		map[string]any{"id": 1, "name": "James"},
+		map[string]any{"id": 2, "name": "Chrisjen"},
+	}))
+
+	// THEN the value is extracted from the paired element
+	assert.Len(t, differences, 0)
+	assert.Equal(t, p(2), context.Get("id"))
+}
+
+func Test_array_matcher_parse(t *testing.T) {
+	assert.Equal(t,
+		&arrayMatcherType{match: matchEach, each: p("$(:int)"), minLength: 2, maxLength: 2},
+		p(map[string]any{"$each": "$(:int)", "$length": 2}),
+	)
+	assert.Panics(t, func() { p(map[string]any{"$each": "$(:int)", "$contains": []any{1}}) })
+	assert.Panics(t, func() { p(map[string]any{"$contains": "world"}) })
+	assert.Panics(t, func() { p(map[string]any{"$length": -1}) })
+	assert.Panics(t, func() { p(map[string]any{"$length": 1, "other": 2}) })
+	assert.Panics(t, func() { p(map[string]any{"$length": 1, "$maxLength": 2}) })
+	assert.Equal(t,
+		&arrayMatcherType{match: matchLength, minLength: 3, maxLength: 3},
+		p(map[string]any{"$length": json.Number("3")}),
+	)
+}
+
+func Test_array_matcher_reports_the_actual_array(t *testing.T) {
+	// GIVEN an expected element not found in the actual array
+	expected := p(map[string]any{"$contains": []any{"moon"}})
+
+	// WHEN compute diff
+	differences := expected.Diff(NewContext(), p([]any{"hello", "world"}))
+
+	// THEN the actual array is written in the difference
+	assert.Equal(t, "0: no matching element.\n  Expected: moon\n  Actual: [hello world]\n", differences.String())
+}
+
+func Test_array_directives_in_the_actual_value_are_compared_as_data(t *testing.T) {
+	// GIVEN a response with a key that looks like an array directive
+	actual := map[string]any{"items": map[string]any{"$length": json.Number("3")}}
+
+	// WHEN it is compared
+	err := NewDiffer(NewContext()).Compare(map[string]any{"items": map[string]any{"$length": 3}}, actual)
+
+	// THEN the key is not read as a directive
+	assert.ErrorContains(t, err, "items: expected array")
+}
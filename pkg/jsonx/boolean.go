@@ -1,6 +1,9 @@
 package jsonx
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 const (
 	Boolean Type = "bool"
@@ -10,6 +13,10 @@ type boolType struct {
 	value bool
 }
 
+func (n *boolType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.value)
+}
+
 func (n *boolType) Equals(_ JsonX) bool {
 	panic("implement boolean.Equal")
 }
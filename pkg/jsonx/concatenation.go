@@ -1,6 +1,9 @@
 package jsonx
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 const Concatenation Type = "concatenation"
 
@@ -8,6 +11,14 @@ type concatenationType struct {
 	values []JsonX
 }
 
+func (n *concatenationType) MarshalJSON() ([]byte, error) {
+	var expression string
+	for _, value := range n.values {
+		expression += value.String()
+	}
+	return json.Marshal(expression)
+}
+
 func (n *concatenationType) String() string {
 	return fmt.Sprintf("%v", n.values)
 }
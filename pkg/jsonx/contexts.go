@@ -30,3 +30,24 @@ func (s *SimpleContext) Set(key string, value any) {
 	fmt.Println("set", key, value)
 	s.vars[key] = value
 }
+
+// scratchContext reads values from its parent, but keeps the values set in its own scope
+type scratchContext struct {
+	parent Context
+	vars   map[string]any
+}
+
+func newScratchContext(parent Context) Context {
+	return &scratchContext{parent: parent, vars: make(map[string]any)}
+}
+
+func (s *scratchContext) Get(key string) any {
+	if value, found := s.vars[key]; found {
+		return value
+	}
+	return s.parent.Get(key)
+}
+
+func (s *scratchContext) Set(key string, value any) {
+	s.vars[key] = value
+}
@@ -0,0 +1,115 @@
+package jsonx
+
+import "fmt"
+
+// MatchMode defines how objects in the actual value are compared with the expected ones
+type MatchMode string
+
+const (
+	// MatchExact reports values in the actual object that are not in the expected one
+	MatchExact MatchMode = "exact"
+	// MatchContains only compares the values in the expected object, extra values are allowed
+	MatchContains MatchMode = "contains"
+)
+
+// modeDirective is a map key that sets the match mode of the object and its children
+const modeDirective = "$mode"
+
+func ParseMatchMode(mode string) (MatchMode, error) {
+	switch MatchMode(mode) {
+	case MatchExact, MatchContains:
+		return MatchMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid match mode '%s', expected '%s' or '%s'", mode, MatchExact, MatchContains)
+}
+
+// NumberMode defines how numbers are compared
+type NumberMode string
+
+const (
+	// NumbersExact expects numbers of the same kind (integer or not) and the same value
+	NumbersExact NumberMode = "exact"
+	// NumbersNumeric expects the same value, integers and floats with same value are equal
+	NumbersNumeric NumberMode = "numeric"
+)
+
+func ParseNumberMode(mode string) (NumberMode, error) {
+	switch NumberMode(mode) {
+	case NumbersExact, NumbersNumeric:
+		return NumberMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid number mode '%s', expected '%s' or '%s'", mode, NumbersExact, NumbersNumeric)
+}
+
+// NumberComparison configures how numbers are compared. In numeric mode, values that differ
+// less than the Absolute tolerance, or less than the Relative tolerance (a fraction of the
+// expected value) are considered equal
+type NumberComparison struct {
+	Mode     NumberMode
+	Absolute float64
+	Relative float64
+}
+
+// DiffOptions configures how Diff compares the values
+type DiffOptions struct {
+	Mode    MatchMode
+	Numbers NumberComparison
+}
+
+var defaultDiffOptions = DiffOptions{
+	Mode:    MatchExact,
+	Numbers: NumberComparison{Mode: NumbersNumeric},
+}
+
+type DiffOption func(options *DiffOptions)
+
+// WithMode sets the match mode used for objects that do not define their own mode
+func WithMode(mode MatchMode) DiffOption {
+	return func(options *DiffOptions) {
+		options.Mode = mode
+	}
+}
+
+// WithNumbers sets how numbers are compared
+func WithNumbers(mode NumberMode) DiffOption {
+	return func(options *DiffOptions) {
+		options.Numbers.Mode = mode
+	}
+}
+
+// WithTolerance sets the absolute and relative tolerance to compare numbers in numeric mode.
+// A zero tolerance is not used
+func WithTolerance(absolute, relative float64) DiffOption {
+	return func(options *DiffOptions) {
+		options.Numbers.Absolute = absolute
+		options.Numbers.Relative = relative
+	}
+}
+
+func NewDiffOptions(options ...DiffOption) DiffOptions {
+	result := defaultDiffOptions
+	for _, option := range options {
+		option(&result)
+	}
+	return result
+}
+
+// diffContext decorates a Context with the options used by Diff, so they reach nested values
+type diffContext struct {
+	Context
+	options DiffOptions
+}
+
+func withDiffOptions(context Context, options DiffOptions) Context {
+	if c, ok := context.(*diffContext); ok {
+		context = c.Context
+	}
+	return &diffContext{Context: context, options: options}
+}
+
+func diffOptionsOf(context Context) DiffOptions {
+	if c, ok := context.(*diffContext); ok {
+		return c.options
+	}
+	return defaultDiffOptions
+}
@@ -60,7 +60,20 @@ func TestDiff(t *testing.T) {
 			"array different length",
 			p([]interface{}{"hello", "world"}),
 			p([]interface{}{"find"}),
-			Differences{{nil, p([]interface{}{"hello", "world"}), p([]interface{}{"hello", "world"}), p([]interface{}{"find"}), "different array lengths"}},
+			Differences{
+				{nil, p([]interface{}{"hello", "world"}), p([]interface{}{"hello", "world"}), p([]interface{}{"find"}), "different array lengths, expected 2, found 1"},
+				{[]string{"0"}, p("hello"), p("hello"), p("find"), "different"},
+				{[]string{"1"}, p("world"), p("world"), p(nil), "missing value"},
+			},
+		},
+		{
+			"array with extra values",
+			p([]interface{}{"hello"}),
+			p([]interface{}{"hello", "world"}),
+			Differences{
+				{nil, p([]interface{}{"hello"}), p([]interface{}{"hello"}), p([]interface{}{"hello", "world"}), "different array lengths, expected 1, found 2"},
+				{[]string{"1"}, p(nil), p(nil), p("world"), "extra value"},
+			},
 		},
 		{
 			"array one different value",
@@ -143,6 +156,74 @@ func TestDiff(t *testing.T) {
 			},
 		},
 		// struct: with expression
+		{
+			"contains mode allows extra values",
+			p(map[string]any{"name": "Chrisjen", "$mode": "contains"}),
+			p(map[string]any{"name": "Chrisjen", "position": "Secretaries-General"}),
+			nil,
+		},
+		{
+			"contains mode still reports missing values",
+			p(map[string]any{"name": "Chrisjen", "age": 42, "$mode": "contains"}),
+			p(map[string]any{"name": "Chrisjen", "position": "Secretaries-General"}),
+			Differences{
+				{[]string{"age"}, p(42), p(42), p(nil), "missing value"},
+			},
+		},
+		{
+			"contains mode applies to nested objects",
+			p(map[string]any{"$mode": "contains", "address": map[string]any{"city": "New York"}}),
+			p(map[string]any{"address": map[string]any{"city": "New York", "state": "NY"}}),
+			nil,
+		},
+		{
+			"exact mode in nested object overrides contains mode",
+			p(map[string]any{"$mode": "contains", "address": map[string]any{"city": "New York", "$mode": "exact"}}),
+			p(map[string]any{"address": map[string]any{"city": "New York", "state": "NY"}}),
+			Differences{
+				{[]string{"state", "address"}, p(nil), p(nil), p("NY"), "extra value"},
+			},
+		},
+		{
+			"any value matches string",
+			p(map[string]any{"id": "$(:any)"}),
+			p(map[string]any{"id": "b5f3a0"}),
+			nil,
+		},
+		{
+			"any value matches map",
+			p(map[string]any{"id": "$(:any)"}),
+			p(map[string]any{"id": map[string]any{"value": 42}}),
+			nil,
+		},
+		{
+			"any value requires the key",
+			p(map[string]any{"id": "$(:any)"}),
+			p(map[string]any{}),
+			Differences{
+				{[]string{"id"}, p("$(:any)"), p("$(:any)"), p(nil), "missing value"},
+			},
+		},
+		{
+			"ignored key is not compared",
+			p(map[string]any{"name": "Chrisjen", "$ignore": "metadata", "metadata": map[string]any{"version": 1}}),
+			p(map[string]any{"name": "Chrisjen", "metadata": map[string]any{"version": 2, "generation": 7}}),
+			nil,
+		},
+		{
+			"ignored keys can be missing or extra",
+			p(map[string]any{"name": "Chrisjen", "$ignore": []any{"metadata", "_links"}, "metadata": map[string]any{"version": 1}}),
+			p(map[string]any{"name": "Chrisjen", "_links": []any{"self"}}),
+			nil,
+		},
+		{
+			"ignored keys do not hide other differences",
+			p(map[string]any{"name": "Chrisjen", "$ignore": []any{"_links"}}),
+			p(map[string]any{"name": "James", "_links": []any{"self"}}),
+			Differences{
+				{[]string{"name"}, p("Chrisjen"), p("Chrisjen"), p("James"), "different"},
+			},
+		},
 		{
 			"multi levels structs with different values",
 			p(struct{ address struct{ city, state string } }{address: struct{ city, state string }{city: "New York", state: "NY"}}),
@@ -1,6 +1,7 @@
 package jsonx
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -13,6 +14,21 @@ type Difference struct {
 	Message     string
 }
 
+// MarshalJSON writes the difference with its path in dot notation
+func (d Difference) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path     string `json:"path"`
+		Message  string `json:"message"`
+		Expected JsonX  `json:"expected"`
+		Actual   JsonX  `json:"actual"`
+	}{
+		Path:     strings.Join(reverse(d.Path), "."),
+		Message:  d.Message,
+		Expected: d.Expected,
+		Actual:   d.Actual,
+	})
+}
+
 func (d Difference) String() string {
 	return fmt.Sprintf("%s: %s.\n  Expected: %s\n  Actual: %s\n", strings.Join(reverse(d.Path), "."), d.Message, d.Expected, d.Actual)
 }
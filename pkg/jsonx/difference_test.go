@@ -0,0 +1,41 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_difference_marshal_json(t *testing.T) {
+	// GIVEN a difference in a nested value
+	differences := p(map[string]any{"address": map[string]any{"city": "New York", "zip": "$(:int)"}}).
+		Diff(NewContext(), p(map[string]any{"address": map[string]any{"city": "Morón", "zip": 1708}}))
+
+	// WHEN it is marshalled
+	result, err := json.Marshal(differences)
+
+	// THEN the path, message and values are written
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"path": "address.city", "message": "different", "expected": "New York", "actual": "Morón"}]`, string(result))
+}
+
+func Test_difference_marshal_json_with_expressions(t *testing.T) {
+	// GIVEN a difference with expressions in the expected value
+	difference := &Difference{
+		Path:     []string{"0", "items"},
+		Expected: p(map[string]any{"id": "$(:uuid)", "name": "$name", "enabled": true, "deleted": nil}),
+		Actual:   p(1.5),
+		Message:  "expected map",
+	}
+
+	// WHEN it is marshalled
+	result, err := json.Marshal(difference)
+
+	// THEN the expressions are written as strings
+	assert.NoError(t, err)
+	assert.JSONEq(t,
+		`{"path": "items.0", "message": "expected map", "expected": {"id": "$(:uuid)", "name": "${name}", "enabled": true, "deleted": null}, "actual": 1.5}`,
+		string(result),
+	)
+}
@@ -29,8 +29,8 @@ func Test_eval(t *testing.T) {
 		{"Another expansions", "The sum of $someInt plus '$$${someInt}' is ${result}", &stringType{"The sum of 987 plus '$987' is I don't know"}},
 		{"Array without expansions", []string{"hello"}, &arrayType{[]JsonX{&stringType{"hello"}}}},
 		{"Array with expansions", []string{"$someVar"}, &arrayType{[]JsonX{&stringType{"hello"}}}},
-		{"Map without expansions", map[string]string{"value1": "my world"}, &mapType{map[string]JsonX{"value1": &stringType{"my world"}}}},
-		{"Map with expansions", map[string]string{"value2": "$someInt"}, &mapType{map[string]JsonX{"value2": &intType{987}}}},
+		{"Map without expansions", map[string]string{"value1": "my world"}, &mapType{values: map[string]JsonX{"value1": &stringType{"my world"}}}},
+		{"Map with expansions", map[string]string{"value2": "$someInt"}, &mapType{values: map[string]JsonX{"value2": &intType{987}}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -87,17 +87,17 @@ func Test_Eval_a_complex_object(t *testing.T) {
 	result := jsonX.Eval(textContext)
 
 	// THEN it should return a JsonX object with the same structure
-	assert.Equal(t, &mapType{map[string]JsonX{
-		"body": &mapType{map[string]JsonX{
+	assert.Equal(t, &mapType{values: map[string]JsonX{
+		"body": &mapType{values: map[string]JsonX{
 			"id":        &stringType{"ALK-00028-Actual"},
 			"partnerId": &stringType{"627e50c8112ee12b37cccede"},
-			"reference": &mapType{map[string]JsonX{
+			"reference": &mapType{values: map[string]JsonX{
 				"id": &stringType{"ALK-00028-B"},
-				"data": &mapType{map[string]JsonX{
+				"data": &mapType{values: map[string]JsonX{
 					"customerType": &stringType{"VIP"},
 				}},
 			}},
-			"flow": &mapType{map[string]JsonX{
+			"flow": &mapType{values: map[string]JsonX{
 				"startDate":   &stringType{"2018-01-01T00:00:00Z"},
 				"state":       &stringType{"in-progress"},
 				"currentStep": &stringType{""},
@@ -105,7 +105,7 @@ func Test_Eval_a_complex_object(t *testing.T) {
 			}},
 			"isApproved":        &boolType{false},
 			"state":             &stringType{"pending"},
-			"data":              &mapType{map[string]JsonX{}},
+			"data":              &mapType{values: map[string]JsonX{}},
 			"sourceConnections": &arrayType{[]JsonX{}},
 		}},
 	}}, result)
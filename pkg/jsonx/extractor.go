@@ -1,13 +1,23 @@
 package jsonx
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 const (
 	Extractor = "Extractor"
 )
 
+// extractorType matches any value, or the values accepted by its matcher, and sets the value
+// in the context when it has a variable name
 type extractorType struct {
 	varName string
+	matcher Matcher
+}
+
+func (e extractorType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
 }
 
 func (e extractorType) Type() Type {
@@ -19,7 +29,13 @@ func (e extractorType) Eval(_ Context) JsonX {
 }
 
 func (e extractorType) Diff(context Context, actual JsonX) Differences {
-	context.Set(e.varName, actual)
+	if e.matcher != nil && !e.matcher.Match(actual) {
+		return Differences{{nil, &e, &e, actual, "expected " + e.matcher.Name()}}
+	}
+
+	if e.varName != "" {
+		context.Set(e.varName, actual)
+	}
 
 	return nil
 }
@@ -29,5 +45,8 @@ func (e extractorType) Equals(_ JsonX) bool {
 }
 
 func (e extractorType) String() string {
-	return "$(" + e.varName + ")"
+	if e.matcher == nil {
+		return "$(" + e.varName + ")"
+	}
+	return "$(" + e.varName + ":" + e.matcher.Name() + ")"
 }
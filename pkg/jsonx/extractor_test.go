@@ -1,6 +1,7 @@
 package jsonx
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +34,77 @@ func Test_extractors_does_not_generate_diff(t *testing.T) {
 	// THEN extractor set value
 	assert.Len(t, differences, 0)
 }
+
+func Test_typed_extractors_set_value_when_type_matches(t *testing.T) {
+	// GIVEN empty context
+	context := NewContext()
+
+	// WHEN compute diff with a value of the expected type
+	differences := p("$(term:int)").Diff(context, p(12))
+
+	// THEN there is no difference and the extractor set value
+	assert.Len(t, differences, 0)
+	assert.Equal(t, p(12), context.Get("term"))
+}
+
+func Test_typed_extractors_report_difference_when_type_does_not_match(t *testing.T) {
+	// GIVEN empty context
+	context := NewContext()
+
+	// WHEN compute diff with a value of another type
+	expected := p("$(term:int)")
+	differences := expected.Diff(context, p("twelve"))
+
+	// THEN there is a difference and the value is not set
+	assert.Equal(t, Differences{{nil, expected, expected, p("twelve"), "expected int"}}, differences)
+	assert.Nil(t, context.Get("term"))
+}
+
+func Test_matchers(t *testing.T) {
+	tests := []struct {
+		matcher string
+		actual  any
+		want    bool
+	}{
+		{"string", "hello", true},
+		{"string", 42, false},
+		{"bool", true, true},
+		{"bool", "true", false},
+		{"int", 42, true},
+		{"int", "42", false},
+		{"int", 42.0, true},
+		{"int", 42.5, false},
+		{"float", 42, true},
+		{"float", 42.5, true},
+		{"float", "42.5", false},
+		{"decimal", 2200.40, true},
+		{"decimal", "2200.40", true},
+		{"decimal", "-12", true},
+		{"decimal", 12, true},
+		{"decimal", "12.", false},
+		{"decimal", "twelve", false},
+		{"uuid", "0b6b9a2e-7f3c-4c5e-9d3a-2f1e8c4b6a70", true},
+		{"uuid", "0b6b9a2e7f3c4c5e9d3a2f1e8c4b6a70", false},
+		{"objectID", "627e50c8112ee12b37cccede", true},
+		{"objectID", "627e50c8112ee12b37cccedX", false},
+		{"datetime", "2018-01-01T00:00:00Z", true},
+		{"datetime", "2018-01-01T10:20:30.123-03:00", true},
+		{"datetime", "2018-01-01T10:20:30.123", true},
+		{"datetime", "2018-01-01", false},
+		{"base64", "aGVsbG8gd29ybGQ=", true},
+		{"base64", "not base 64!", false},
+		{"/[a-z]+/", "hello", true},
+		{"/[a-z]+/", "hello world", false},
+		{"/[0-9]{3}/", 123, true},
+		{"/[0-9]+\\.[0-9]{2}/", 22.75, true},
+		{"/[0-9]{3}/", []string{"123"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s with %v", tt.matcher, tt.actual), func(t *testing.T) {
+			matcher, err := NewMatcher(tt.matcher)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, matcher.Match(p(tt.actual)))
+		})
+	}
+}
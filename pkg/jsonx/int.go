@@ -22,12 +22,8 @@ func (n *intType) Equals(value JsonX) bool {
 	return ok && other.value == n.value
 }
 
-func (n *intType) Diff(_ Context, actual JsonX) Differences {
-	if n.Equals(actual) {
-		return nil
-	}
-
-	return Differences{{nil, n, n, actual, "different"}}
+func (n *intType) Diff(context Context, actual JsonX) Differences {
+	return diffNumbers(context, n, actual)
 }
 
 func (n *intType) Eval(_ Context) JsonX {
@@ -3,24 +3,34 @@ package jsonx
 import "fmt"
 
 type Differ struct {
-	context     Context
-	parser      Parser
-	differences Differences
+	context Context
+	parser  Parser
+	// actualParser reads the actual values as data, so they cannot change the comparison
+	actualParser Parser
+	options      DiffOptions
+	differences  Differences
 }
 
-func NewDiffer(context Context) *Differ {
-	return &Differ{context: context, parser: NewParser()}
+func NewDiffer(context Context, options ...DiffOption) *Differ {
+	return &Differ{context: context, parser: NewParser(), actualParser: NewPlainParser(), options: NewDiffOptions(options...)}
 }
 
 func (d *Differ) Compare(expected, actual any) error {
-	actualJsonX := d.parser.Parse(actual)
-	expectedJsonX := d.parser.Parse(expected)
+	actualJsonX, err := d.actualParser.TryParse(actual)
+	if err != nil {
+		return err
+	}
+	expectedJsonX, err := d.parser.TryParse(expected)
+	if err != nil {
+		return fmt.Errorf("invalid expected value: %w", err)
+	}
+	context := withDiffOptions(d.context, d.options)
 
 	// Compare first to process all extractors so varExpansions will have the corresponding values
 	// TODO improve this to do everything in one pass (visit map entries in original order, allow extractors in expressions, etc)
-	_ = expectedJsonX.Diff(d.context, actualJsonX)
+	_ = expectedJsonX.Diff(context, actualJsonX)
 
-	d.differences = expectedJsonX.Diff(d.context, actualJsonX)
+	d.differences = expectedJsonX.Diff(context, actualJsonX)
 
 	if len(d.differences) == 0 {
 		return nil
@@ -0,0 +1,81 @@
+package jsonx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_differ_reports_extra_values_by_default(t *testing.T) {
+	// GIVEN a differ with default options
+	differ := NewDiffer(NewContext())
+
+	// WHEN the actual value has more values than the expected
+	err := differ.Compare(
+		map[string]any{"name": "Chrisjen"},
+		map[string]any{"name": "Chrisjen", "position": "Secretaries-General"},
+	)
+
+	// THEN the extra value is reported
+	assert.Error(t, err)
+	assert.Equal(t, Differences{{[]string{"position"}, p(nil), p(nil), p("Secretaries-General"), "extra value"}}, differ.Differences())
+}
+
+func Test_differ_with_contains_mode_allows_extra_values(t *testing.T) {
+	// GIVEN a differ in contains mode
+	context := NewContext()
+	differ := NewDiffer(context, WithMode(MatchContains))
+
+	// WHEN the actual value has more values than the expected
+	err := differ.Compare(
+		map[string]any{"id": "$(id)", "address": map[string]any{"city": "New York"}},
+		map[string]any{"id": "a1", "address": map[string]any{"city": "New York", "state": "NY"}, "position": "Secretaries-General"},
+	)
+
+	// THEN no difference is reported and the extractors still set values
+	assert.NoError(t, err)
+	assert.Equal(t, p("a1"), context.Get("id"))
+}
+
+func Test_differ_with_contains_mode_respects_exact_objects(t *testing.T) {
+	// GIVEN a differ in contains mode
+	differ := NewDiffer(NewContext(), WithMode(MatchContains))
+
+	// WHEN the expected object asks for exact mode
+	err := differ.Compare(
+		map[string]any{"name": "Chrisjen", "$mode": "exact"},
+		map[string]any{"name": "Chrisjen", "position": "Secretaries-General"},
+	)
+
+	// THEN the extra value is reported
+	assert.Error(t, err)
+}
+
+func Test_differ_compares_directives_in_the_actual_value_as_data(t *testing.T) {
+	// GIVEN a differ with default options
+	differ := NewDiffer(NewContext())
+
+	// WHEN the actual value has keys and strings that look like directives and expressions
+	err := differ.Compare(
+		map[string]any{"name": "Chrisjen", "price": "$$5"},
+		map[string]any{"name": "Chrisjen", "price": "$5", "$ignore": "name"},
+	)
+
+	// THEN they are compared as any other value
+	assert.Error(t, err)
+	assert.Equal(t, Differences{{[]string{"$ignore"}, p(nil), p(nil), &stringType{"name"}, "extra value"}}, differ.Differences())
+}
+
+func Test_differ_returns_invalid_expected_values(t *testing.T) {
+	// GIVEN a differ with default options
+	differ := NewDiffer(NewContext())
+
+	// WHEN the expected value has an invalid mode, and the actual value a mode key
+	err := differ.Compare(
+		map[string]any{"$mode": "partial"},
+		map[string]any{"$mode": "contains"},
+	)
+
+	// THEN the error is returned
+	assert.EqualError(t, err, "invalid expected value: invalid $mode directive: invalid match mode 'partial', expected 'exact' or 'contains'")
+}
@@ -11,6 +11,10 @@ const (
 
 type mapType struct {
 	values map[string]JsonX
+	// ignored are the keys whose values are not compared, they can be missing or have any value
+	ignored map[string]bool
+	// mode overrides the match mode for this object and its children, if not empty
+	mode MatchMode
 }
 
 func (n *mapType) MarshalJSON() ([]byte, error) {
@@ -31,9 +35,18 @@ func (n *mapType) Diff(context Context, actual JsonX) Differences {
 		return Differences{{nil, n, n, actual, "expected map"}}
 	}
 
+	options := diffOptionsOf(context)
+	if n.mode != "" && n.mode != options.Mode {
+		options.Mode = n.mode
+		context = withDiffOptions(context, options)
+	}
+
 	var differences []*Difference
 
 	for key, value := range n.values {
+		if n.ignored[key] {
+			continue
+		}
 		otherValue, ok := otherMap.values[key]
 		if !ok {
 			differences = append(differences, &Difference{[]string{key}, value, value, NullX, "missing value"})
@@ -43,8 +56,12 @@ func (n *mapType) Diff(context Context, actual JsonX) Differences {
 		}
 	}
 
+	if options.Mode == MatchContains {
+		return differences
+	}
+
 	for key, value := range otherMap.values {
-		if _, ok := n.values[key]; !ok {
+		if _, ok := n.values[key]; !ok && !n.ignored[key] {
 			differences = append(differences, &Difference{[]string{key}, NullX, NullX, value, "extra value"})
 		}
 	}
@@ -0,0 +1,167 @@
+package jsonx
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Matcher checks if an actual value satisfies a type or format. Matchers are used by
+// extractors like $(varName:type) or $(:type)
+type Matcher interface {
+	// Name is the name used to reference the matcher in an extractor
+	Name() string
+	// Match returns true if the actual value is of the matcher type or format
+	Match(actual JsonX) bool
+}
+
+type funcMatcher struct {
+	name  string
+	match func(actual JsonX) bool
+}
+
+func (m *funcMatcher) Name() string {
+	return m.name
+}
+
+func (m *funcMatcher) Match(actual JsonX) bool {
+	return m.match(actual)
+}
+
+type regexMatcher struct {
+	expression string
+	regex      *regexp.Regexp
+}
+
+func (m *regexMatcher) Name() string {
+	return "/" + m.expression + "/"
+}
+
+// Match returns true if the whole scalar value matches the regular expression
+func (m *regexMatcher) Match(actual JsonX) bool {
+	value, ok := scalarString(actual)
+	return ok && m.regex.MatchString(value)
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+var objectIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+var decimalRegex = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+
+// dateTimeLayouts are the accepted ISO 8601 layouts, with and without time zone
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+var matchers = map[string]Matcher{
+	"any":      &funcMatcher{"any", isAny},
+	"string":   &funcMatcher{"string", isString},
+	"bool":     &funcMatcher{"bool", isBool},
+	"int":      &funcMatcher{"int", isInt},
+	"float":    &funcMatcher{"float", isNumber},
+	"decimal":  &funcMatcher{"decimal", isDecimal},
+	"uuid":     &funcMatcher{"uuid", stringMatching(uuidRegex.MatchString)},
+	"objectID": &funcMatcher{"objectID", stringMatching(objectIDRegex.MatchString)},
+	"datetime": &funcMatcher{"datetime", stringMatching(isDateTime)},
+	"base64":   &funcMatcher{"base64", stringMatching(isBase64)},
+}
+
+// NewMatcher returns the matcher for the given type name, or for the regular expression
+// if the name is enclosed in slashes
+func NewMatcher(name string) (Matcher, error) {
+	if len(name) >= 2 && name[0] == regexStartEnd && name[len(name)-1] == regexStartEnd {
+		return newRegexMatcher(name[1 : len(name)-1])
+	}
+
+	if matcher, found := matchers[name]; found {
+		return matcher, nil
+	}
+
+	return nil, fmt.Errorf("unknown matcher type: %s", name)
+}
+
+func newRegexMatcher(expression string) (Matcher, error) {
+	// The regex should match the whole value
+	regex, err := regexp.Compile("^(?:" + expression + ")$")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &regexMatcher{expression: expression, regex: regex}, nil
+}
+
+func isAny(_ JsonX) bool {
+	return true
+}
+
+func isString(actual JsonX) bool {
+	_, ok := actual.(*stringType)
+	return ok
+}
+
+func isBool(actual JsonX) bool {
+	_, ok := actual.(*boolType)
+	return ok
+}
+
+func isInt(actual JsonX) bool {
+	switch number := actual.(type) {
+	case *intType:
+		return true
+	case *numberType:
+		return number.value.IsInt()
+	}
+	return false
+}
+
+func isNumber(actual JsonX) bool {
+	_, ok := ratOf(actual)
+	return ok
+}
+
+// isDecimal accepts numbers, and strings with a decimal number representation like "2200.40"
+func isDecimal(actual JsonX) bool {
+	if str, ok := actual.(*stringType); ok {
+		return decimalRegex.MatchString(str.value)
+	}
+	return isNumber(actual)
+}
+
+func isDateTime(value string) bool {
+	for _, layout := range dateTimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isBase64(value string) bool {
+	_, err := base64.StdEncoding.DecodeString(value)
+	return err == nil
+}
+
+func stringMatching(match func(string) bool) func(actual JsonX) bool {
+	return func(actual JsonX) bool {
+		str, ok := actual.(*stringType)
+		return ok && match(str.value)
+	}
+}
+
+// scalarString returns the string representation of strings, numbers and booleans
+func scalarString(actual JsonX) (string, bool) {
+	switch value := actual.(type) {
+	case *stringType:
+		return value.value, true
+	case *intType:
+		return strconv.FormatInt(value.value, 10), true
+	case *numberType:
+		return value.text, true
+	case *boolType:
+		return strconv.FormatBool(value.value), true
+	}
+	return "", false
+}
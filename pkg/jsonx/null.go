@@ -9,6 +9,10 @@ type nullType struct {
 
 var NullX = &nullType{}
 
+func (n *nullType) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
 func (n *nullType) Eval(_ Context) JsonX {
 	return n
 }
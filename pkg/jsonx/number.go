@@ -0,0 +1,132 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+const (
+	Number Type = "number"
+)
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// numberType is a number with a fractional part, or an integer too big for intType. The value
+// has arbitrary precision, so decimals like 2200.40 are kept without rounding
+type numberType struct {
+	value *big.Rat
+	text  string
+}
+
+// newNumber returns the JsonX node for the number in text, an intType if it is a valid int64
+func newNumber(text string) (JsonX, bool) {
+	if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return &intType{value: value}, true
+	}
+
+	value, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, false
+	}
+
+	return &numberType{value: value, text: text}, true
+}
+
+// newFloat returns the number with the shortest decimal text of the float of the given bit size, so float32
+// values are not compared with the rounding error of their float64 conversion
+func newFloat(value float64, bitSize int) JsonX {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return NullX
+	}
+
+	text := strconv.FormatFloat(value, 'f', -1, bitSize)
+	number, _ := new(big.Rat).SetString(text)
+
+	return &numberType{value: number, text: text}
+}
+
+func (n *numberType) MarshalJSON() ([]byte, error) {
+	return []byte(n.text), nil
+}
+
+func (n *numberType) Equals(value JsonX) bool {
+	other, ok := value.(*numberType)
+	return ok && other.value.Cmp(n.value) == 0
+}
+
+func (n *numberType) Diff(context Context, actual JsonX) Differences {
+	return diffNumbers(context, n, actual)
+}
+
+func (n *numberType) Eval(_ Context) JsonX {
+	return n
+}
+
+func (n *numberType) Type() Type {
+	return Number
+}
+
+func (n *numberType) String() string {
+	return n.text
+}
+
+func diffNumbers(context Context, expected JsonX, actual JsonX) Differences {
+	if numbersEqual(diffOptionsOf(context).Numbers, expected, actual) {
+		return nil
+	}
+
+	return Differences{{nil, expected, expected, actual, "different"}}
+}
+
+// numbersEqual compares two numeric nodes using the given comparison
+func numbersEqual(comparison NumberComparison, expected JsonX, actual JsonX) bool {
+	expectedValue, ok := ratOf(expected)
+	if !ok {
+		return false
+	}
+	actualValue, ok := ratOf(actual)
+	if !ok {
+		return false
+	}
+
+	if comparison.Mode == NumbersExact {
+		return expected.Type() == actual.Type() && expectedValue.Cmp(actualValue) == 0
+	}
+
+	difference := new(big.Rat).Sub(expectedValue, actualValue)
+	difference.Abs(difference)
+
+	if difference.Sign() == 0 {
+		return true
+	}
+
+	if comparison.Absolute > 0 && difference.Cmp(ratOfFloat(comparison.Absolute)) <= 0 {
+		return true
+	}
+
+	if comparison.Relative > 0 {
+		limit := new(big.Rat).Abs(expectedValue)
+		limit.Mul(limit, ratOfFloat(comparison.Relative))
+		return difference.Cmp(limit) <= 0
+	}
+
+	return false
+}
+
+func ratOf(value JsonX) (*big.Rat, bool) {
+	switch number := value.(type) {
+	case *intType:
+		return new(big.Rat).SetInt64(number.value), true
+	case *numberType:
+		return number.value, true
+	}
+	return nil, false
+}
+
+func ratOfFloat(value float64) *big.Rat {
+	rat, _ := new(big.Rat).SetString(strconv.FormatFloat(value, 'f', -1, 64))
+	return rat
+}
@@ -0,0 +1,102 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parse_numbers(t *testing.T) {
+	tests := []struct {
+		name       string
+		jsonObject interface{}
+		want       JsonX
+		ofType     Type
+	}{
+		{"int64", int64(42), &intType{42}, Int},
+		{"uint8", uint8(42), &intType{42}, Int},
+		{"float", 2200.40, &numberType{big.NewRat(11002, 5), "2200.4"}, Number},
+		{"integral float", 42.0, &numberType{big.NewRat(42, 1), "42"}, Number},
+		{"float32", float32(0.1), &numberType{big.NewRat(1, 10), "0.1"}, Number},
+		{"json integer", json.Number("42"), &intType{42}, Int},
+		{"json decimal", json.Number("2200.40"), &numberType{big.NewRat(11002, 5), "2200.40"}, Number},
+		{"json big integer", json.Number("12345678901234567890"), &numberType{ratOfText("12345678901234567890"), "12345678901234567890"}, Number},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := NewParser().Parse(tt.jsonObject)
+			assert.Equalf(t, tt.ofType, parsed.Type(), "Parse(%v) type", tt.jsonObject)
+			assert.Equalf(t, tt.want, parsed, "Parse(%v)", tt.jsonObject)
+		})
+	}
+}
+
+func Test_parse_json_keeps_decimal_precision(t *testing.T) {
+	// GIVEN a JSON decoded with numbers
+	decoder := json.NewDecoder(strings.NewReader(`{"amount": 12345678901234567890.12}`))
+	decoder.UseNumber()
+	var body any
+	_ = decoder.Decode(&body)
+
+	// WHEN it is parsed and marshalled again
+	result, err := json.Marshal(p(body))
+
+	// THEN the number is not rounded
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":12345678901234567890.12}`, string(result))
+}
+
+func Test_diff_numbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []DiffOption
+		expected any
+		actual   any
+		equal    bool
+	}{
+		{"same ints", nil, 42, 42, true},
+		{"same floats", nil, 2200.4, 2200.4, true},
+		{"float and decimal", nil, 2200.4, json.Number("2200.40"), true},
+		{"int and float", nil, 42, 42.0, true},
+		{"int and json number", nil, 42, json.Number("42.0"), true},
+		{"different floats", nil, 2200.4, 2200.41, false},
+		{"number and string", nil, 2200.4, "2200.4", false},
+		{"exact ints", []DiffOption{WithNumbers(NumbersExact)}, 42, 42, true},
+		{"exact int and float", []DiffOption{WithNumbers(NumbersExact)}, 42, 42.0, false},
+		{"exact float and decimal", []DiffOption{WithNumbers(NumbersExact)}, 2200.4, json.Number("2200.40"), true},
+		{"inside absolute tolerance", []DiffOption{WithTolerance(0.01, 0)}, 2200.4, 2200.41, true},
+		{"outside absolute tolerance", []DiffOption{WithTolerance(0.01, 0)}, 2200.4, 2200.42, false},
+		{"inside relative tolerance", []DiffOption{WithTolerance(0, 0.001)}, 1000, 1001, true},
+		{"outside relative tolerance", []DiffOption{WithTolerance(0, 0.001)}, 1000, 1002, false},
+		{"tolerance is not used in exact mode", []DiffOption{WithNumbers(NumbersExact), WithTolerance(1, 0)}, 1000, 1001, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			differ := NewDiffer(NewContext(), tt.options...)
+
+			err := differ.Compare(map[string]any{"value": tt.expected}, map[string]any{"value": tt.actual})
+
+			assert.Equalf(t, tt.equal, err == nil, "Compare(%v, %v): %v", tt.expected, tt.actual, err)
+		})
+	}
+}
+
+func Test_diff_numbers_with_expressions(t *testing.T) {
+	// GIVEN a context with an int value
+	context := NewContext()
+	context.Set("amount", 42)
+
+	// WHEN compared with a float of the same value
+	differences := p("$amount").Diff(context, p(42.0))
+
+	// THEN there are no differences
+	assert.Len(t, differences, 0)
+}
+
+func ratOfText(text string) *big.Rat {
+	rat, _ := new(big.Rat).SetString(text)
+	return rat
+}
@@ -3,6 +3,7 @@ package jsonx
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 )
 
 const placeholderStart = '$'
@@ -14,28 +15,63 @@ const varExtractorEnd = ')'
 const regexStartEnd = '/'
 const regexEscapeChar = '\\'
 
+// ignoreDirective is a map key that lists the keys whose values should not be compared
+const ignoreDirective = "$ignore"
+
 func NewParser() Parser {
 	return &parser{}
 }
 
+// NewPlainParser returns a parser that reads the values as data, e.g. the actual values of a response.
+// Strings are not parsed as expressions, and map keys are not read as directives
+func NewPlainParser() Parser {
+	return &parser{plain: true}
+}
+
 type parser struct {
+	// plain parses strings and maps as literal values, without expressions or directives
+	plain bool
 }
 
+// Parse parses the value. It panics if the value has invalid expressions or directives, use Validate to
+// check the values read from the specs
 func (p *parser) Parse(jsonObject interface{}) JsonX {
-	value := reflect.ValueOf(jsonObject)
-
-	x := p.parse(value)
+	x, err := p.TryParse(jsonObject)
+	if err != nil {
+		panic(err)
+	}
 	return x
 }
 
-func (p *parser) parse(value reflect.Value) JsonX {
+// TryParse parses the value, and returns an error if it has invalid expressions or directives
+func (p *parser) TryParse(jsonObject interface{}) (JsonX, error) {
+	return p.parse(reflect.ValueOf(jsonObject))
+}
+
+// Validate returns an error if the value has invalid expressions or directives
+func Validate(jsonObject interface{}) error {
+	_, err := (&parser{}).TryParse(jsonObject)
+	return err
+}
+
+func (p *parser) parse(value reflect.Value) (JsonX, error) {
 	switch value.Kind() {
 	case reflect.Invalid:
-		return NullX
+		return NullX, nil
 	case reflect.String:
+		if value.Type() == jsonNumberType {
+			return p.parseNumber(value)
+		}
 		return p.parseString(value)
-	case reflect.Int:
-		return &intType{value: value.Int()}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &intType{value: value.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, _ := newNumber(strconv.FormatUint(value.Uint(), 10))
+		return number, nil
+	case reflect.Float32:
+		return newFloat(value.Float(), 32), nil
+	case reflect.Float64:
+		return newFloat(value.Float(), 64), nil
 	case reflect.Slice:
 		return p.parseSlice(value)
 	case reflect.Array:
@@ -45,54 +81,132 @@ func (p *parser) parse(value reflect.Value) JsonX {
 	case reflect.Struct:
 		return p.parseStruct(value)
 	case reflect.Bool:
-		return &boolType{value: value.Bool()}
+		return &boolType{value: value.Bool()}, nil
 	case reflect.Interface:
 		return p.parse(value.Elem())
 	case reflect.Ptr:
 		// Improve me
 		if value.CanInterface() {
 			if v, ok := value.Interface().(JsonX); ok {
-				return v
+				return v, nil
 			}
 		}
 		return p.parse(value.Elem())
 	}
-	return &nullType{} // error
+	return &nullType{}, nil // error
 }
 
-func (p *parser) parseString(value reflect.Value) JsonX {
+func (p *parser) parseString(value reflect.Value) (JsonX, error) {
+	if p.plain {
+		return &stringType{value: value.String()}, nil
+	}
 	return p.parseExpression(value.String())
 }
 
-func (p *parser) parseSlice(value reflect.Value) JsonX {
+// parseNumber parses numbers decoded with json.Decoder.UseNumber, keeping their precision
+func (p *parser) parseNumber(value reflect.Value) (JsonX, error) {
+	number, ok := newNumber(value.String())
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", value.String())
+	}
+	return number, nil
+}
+
+func (p *parser) parseSlice(value reflect.Value) (JsonX, error) {
 	result := make([]JsonX, value.Len())
 	for i := 0; i < value.Len(); i++ {
-		result[i] = p.parse(value.Index(i))
+		element, err := p.parse(value.Index(i))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", i, err)
+		}
+		result[i] = element
 	}
-	return &arrayType{values: result}
+	return &arrayType{values: result}, nil
 }
 
-func (p *parser) parseStruct(value reflect.Value) JsonX {
+func (p *parser) parseStruct(value reflect.Value) (JsonX, error) {
 	result := make(map[string]JsonX, value.NumField())
 	typeOfS := value.Type()
 	for i := 0; i < value.NumField(); i++ {
-		result[typeOfS.Field(i).Name] = p.parse(value.Field(i))
+		field, err := p.parse(value.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typeOfS.Field(i).Name, err)
+		}
+		result[typeOfS.Field(i).Name] = field
 	}
 
-	return &mapType{result}
+	return &mapType{values: result}, nil
 }
 
-func (p *parser) parseMap(value reflect.Value) JsonX {
+func (p *parser) parseMap(value reflect.Value) (JsonX, error) {
+	if !p.plain && isArrayMatcher(value) {
+		return p.parseArrayMatcher(value)
+	}
+
 	result := make(map[string]JsonX, value.Len())
+	var ignored map[string]bool
+	var mode MatchMode
+	var err error
+
 	for _, key := range value.MapKeys() {
 		keyStr := fmt.Sprintf("%v", key.Interface())
-		result[keyStr] = p.parse(value.MapIndex(key))
+
+		switch {
+		case !p.plain && keyStr == ignoreDirective:
+			ignored, err = parseIgnoreDirective(value.MapIndex(key))
+		case !p.plain && keyStr == modeDirective:
+			mode, err = parseModeDirective(value.MapIndex(key))
+		default:
+			if result[keyStr], err = p.parse(value.MapIndex(key)); err != nil {
+				err = fmt.Errorf("%s: %w", keyStr, err)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &mapType{result}
+	return &mapType{values: result, ignored: ignored, mode: mode}, nil
+}
+
+func parseModeDirective(value reflect.Value) (MatchMode, error) {
+	mode, err := ParseMatchMode(fmt.Sprintf("%v", value.Interface()))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s directive: %w", modeDirective, err)
+	}
+	return mode, nil
 }
 
-func (p *parser) parseExpression(s string) JsonX {
+// parseIgnoreDirective reads the key, or list of keys, whose values should not be compared
+func parseIgnoreDirective(value reflect.Value) (map[string]bool, error) {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	ignored := make(map[string]bool)
+
+	switch value.Kind() {
+	case reflect.String:
+		ignored[value.String()] = true
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			keys, err := parseIgnoreDirective(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			for key := range keys {
+				ignored[key] = true
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s directive: expected a key or a list of keys", ignoreDirective)
+	}
+
+	return ignored, nil
+}
+
+func (p *parser) parseExpression(s string) (JsonX, error) {
 	l := len(s)
 	values := make([]JsonX, 0, 1)
 
@@ -104,7 +218,7 @@ func (p *parser) parseExpression(s string) JsonX {
 			var err error
 			element, pos, err = parseVarOrDollar(s, pos+1, l)
 			if err != nil {
-				panic(err)
+				return nil, err
 			}
 		} else {
 			element, pos = parseLiteral(s, pos, l)
@@ -114,9 +228,9 @@ func (p *parser) parseExpression(s string) JsonX {
 	}
 
 	if len(values) == 1 {
-		return values[0]
+		return values[0], nil
 	}
-	return &concatenationType{values: values}
+	return &concatenationType{values: values}, nil
 }
 
 func parseLiteral(s string, pos int, l int) (JsonX, int) {
@@ -177,7 +291,7 @@ func parseVarExtended(s string, pos int, l int) (JsonX, int, error) {
 
 func parseExtractor(s string, pos int, l int) (JsonX, int, error) {
 	end := pos + 1
-	for end < l && s[end] != varExtractorEnd {
+	for end < l && s[end] != varExtractorEnd && s[end] != optionsSeparator {
 		end++
 	}
 	if end >= l {
@@ -186,7 +300,38 @@ func parseExtractor(s string, pos int, l int) (JsonX, int, error) {
 
 	name := s[pos+1 : end]
 
-	return &extractorType{varName: name}, end + 1, nil // TODO
+	if s[end] == optionsSeparator {
+		return parseExtractorWithMatcher(name, s, end+1, l)
+	}
+
+	return &extractorType{varName: name}, end + 1, nil
+}
+
+func parseExtractorWithMatcher(name string, s string, pos int, l int) (JsonX, int, error) {
+	end := pos
+	if end < l && s[end] == regexStartEnd {
+		_, regexEnd, err := parseRegex(s, pos, l)
+		if err != nil {
+			return nil, regexEnd, err
+		}
+		end = regexEnd
+	} else {
+		for end < l && s[end] != varExtractorEnd {
+			end++
+		}
+	}
+
+	if end >= l || s[end] != varExtractorEnd {
+		return nil, end, NewInvalidExpression(s, pos)
+	}
+
+	matcher, err := NewMatcher(s[pos:end])
+
+	if err != nil {
+		return nil, end, fmt.Errorf("%s: %w", NewInvalidExpression(s, pos), err)
+	}
+
+	return &extractorType{varName: name, matcher: matcher}, end + 1, nil
 }
 
 func parseVarWithGenerator(name string, s string, pos int, l int) (JsonX, int, error) {
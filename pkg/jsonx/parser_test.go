@@ -21,24 +21,38 @@ func Test_parser_Parse(t *testing.T) {
 		{"String", "hello", &stringType{"hello"}, String},
 		{"Int", 123, &intType{123}, Int},
 		{"Array", []string{"hello"}, &arrayType{[]JsonX{&stringType{"hello"}}}, Array},
-		{"Map", map[string]string{"value1": "hello"}, &mapType{map[string]JsonX{"value1": &stringType{"hello"}}}, Map},
+		{"Map", map[string]string{"value1": "hello"}, &mapType{values: map[string]JsonX{"value1": &stringType{"hello"}}}, Map},
 		{
 			"complex Map",
 			map[string]string{"value1": "hello", "value2": "chao"},
-			&mapType{map[string]JsonX{"value1": &stringType{"hello"}, "value2": &stringType{"chao"}}},
+			&mapType{values: map[string]JsonX{"value1": &stringType{"hello"}, "value2": &stringType{"chao"}}},
 			Map,
 		},
 		{
 			"interface Map",
 			map[interface{}]string{"value1": "hello", "value2": "chao"},
-			&mapType{map[string]JsonX{"value1": &stringType{"hello"}, "value2": &stringType{"chao"}}},
+			&mapType{values: map[string]JsonX{"value1": &stringType{"hello"}, "value2": &stringType{"chao"}}},
+			Map,
+		},
+
+		{
+			"Map with ignored keys",
+			map[string]any{"value1": "hello", "$ignore": []string{"_links", "metadata"}},
+			&mapType{values: map[string]JsonX{"value1": &stringType{"hello"}}, ignored: map[string]bool{"_links": true, "metadata": true}},
+			Map,
+		},
+
+		{
+			"Map with mode",
+			map[string]any{"value1": "hello", "$mode": "contains"},
+			&mapType{values: map[string]JsonX{"value1": &stringType{"hello"}}, mode: MatchContains},
 			Map,
 		},
 
 		{"Struct", sampleStruct{
 			String: "hi",
 			Int:    42,
-		}, &mapType{map[string]JsonX{"String": &stringType{"hi"}, "Int": &intType{42}}}, Map},
+		}, &mapType{values: map[string]JsonX{"String": &stringType{"hi"}, "Int": &intType{42}}}, Map},
 		{"escaped $", "$$", &stringType{"$"}, String},
 		{"doubled escaped $ ", "$$$$", &concatenationType{[]JsonX{
 			&stringType{"$"},
@@ -78,13 +92,13 @@ func Test_parser_Parse(t *testing.T) {
 		{
 			"Interface",
 			struct{ x interface{} }{x: asInterface("hello")},
-			&mapType{map[string]JsonX{"x": &stringType{"hello"}}},
+			&mapType{values: map[string]JsonX{"x": &stringType{"hello"}}},
 			Map,
 		},
 		{
 			"Pointer to",
 			struct{ x *string }{x: asPointer("hello")},
-			&mapType{map[string]JsonX{"x": &stringType{"hello"}}},
+			&mapType{values: map[string]JsonX{"x": &stringType{"hello"}}},
 			Map,
 		},
 
@@ -97,6 +111,10 @@ func Test_parser_Parse(t *testing.T) {
 
 		// Extractors
 		{"Extractor", "$(varToSet)", &extractorType{varName: "varToSet"}, Extractor},
+		{"Extractor with type", "$(term:int)", &extractorType{varName: "term", matcher: matchers["int"]}, Extractor},
+		{"Matcher without name", "$(:uuid)", &extractorType{matcher: matchers["uuid"]}, Extractor},
+		{"Matcher with regex", "$(:/[a-z]+/)", &extractorType{matcher: mustMatcher("/[a-z]+/")}, Extractor},
+		{"Extractor with escaped regex", `$(code:/[A-Z]{3}\/(0|1)/)`, &extractorType{varName: "code", matcher: mustMatcher(`/[A-Z]{3}\/(0|1)/`)}, Extractor},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -108,6 +126,28 @@ func Test_parser_Parse(t *testing.T) {
 	}
 }
 
+func Test_parser_Parse_invalid_expressions(t *testing.T) {
+	assert.Panics(t, func() { NewParser().Parse("$(term:unknown)") })
+	assert.Panics(t, func() { NewParser().Parse("$(term:/[a-z/)") })
+	assert.Panics(t, func() { NewParser().Parse("$(term:int") })
+	assert.Panics(t, func() { NewParser().Parse(map[string]any{"$ignore": 42}) })
+	assert.Panics(t, func() { NewParser().Parse(map[string]any{"$mode": "partial"}) })
+}
+
+func Test_validate_returns_invalid_expressions_and_directives(t *testing.T) {
+	assert.NoError(t, Validate(map[string]any{"id": "$(id:int)", "$mode": "contains"}))
+	assert.EqualError(t, Validate(map[string]any{"id": "$(id:int"}), "id: invalid expression: $(id:int at 5")
+	assert.EqualError(t, Validate(map[string]any{"$mode": "partial"}), "invalid $mode directive: invalid match mode 'partial', expected 'exact' or 'contains'")
+}
+
+func mustMatcher(name string) Matcher {
+	matcher, err := NewMatcher(name)
+	if err != nil {
+		panic(err)
+	}
+	return matcher
+}
+
 func asPointer[T any](t T) *T {
 	return &t
 }
@@ -1,6 +1,7 @@
 package jsonx
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/brianvoe/gofakeit/v6"
@@ -47,6 +48,10 @@ func NewRandomValue(name, typeName, config string) *randomValueType {
 	return &randomValueType{name: name, _type: _type, config: config}
 }
 
+func (n *randomValueType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
 func (n *randomValueType) Equals(other JsonX) bool {
 	panic("implement randomValue.Equals")
 }
@@ -11,8 +11,8 @@ type JsonX interface {
 	// First value is the expected one, second is the actual value. The expected value can contain
 	// placeholders for context variables, but also value extractors.
 	// If it is a placeholder, the context value will be used to compare. If it is an extractor,
-	// the value will be set in the context and the comparison will success. (Note: When adding format
-	// matchers the extractor or matcher will check the expected.)
+	// the actual value is checked against the extractor matcher (if any), and if it has a name
+	// the value will be set in the context.
 	Diff(context Context, actual JsonX) Differences
 
 	// Equals compares this JsonX with another JsonX and returns true if they are same type and same value.
@@ -23,4 +23,5 @@ type JsonX interface {
 
 type Parser interface {
 	Parse(jsonObject interface{}) JsonX
+	TryParse(jsonObject interface{}) (JsonX, error)
 }
@@ -1,5 +1,7 @@
 package jsonx
 
+import "encoding/json"
+
 const (
 	VarExpansion Type = "varExpansion"
 )
@@ -8,6 +10,10 @@ type varExpansionType struct {
 	varName string
 }
 
+func (n *varExpansionType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
 func (n *varExpansionType) Equals(actual JsonX) bool {
 	panic("implement varExpansion.Equals")
 }
@@ -15,7 +21,8 @@ func (n *varExpansionType) Equals(actual JsonX) bool {
 func (n *varExpansionType) Diff(context Context, actual JsonX) Differences {
 	expected := n.Eval(context)
 
-	if expected.Equals(actual) {
+	// Compare the evaluated value, so numbers are compared as configured
+	if len(expected.Diff(context, actual)) == 0 {
 		return nil
 	}
 